@@ -1,20 +1,29 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
+	"os"
+
 	"github.com/alexflint/go-arg"
 
 	"github.com/soyart/ssg/soyweb"
+	"github.com/soyart/ssg/ssg-go"
 )
 
 type cli struct {
-	Build   *cmdBuild `arg:"subcommand:build"`
-	Copy    *cmdOther `arg:"subcommand:copy"`
-	Clean   *cmdOther `arg:"subcommand:clean"`   // Same with cleanup
-	CleanUp *cmdOther `arg:"subcommand:cleanup"` // Same with clean
+	Build    *cmdBuild  `arg:"subcommand:build"`
+	Copy     *cmdOther  `arg:"subcommand:copy"`
+	Clean    *cmdOther  `arg:"subcommand:clean"`   // Same with cleanup
+	CleanUp  *cmdOther  `arg:"subcommand:cleanup"` // Same with clean
+	Check    *manifests `arg:"subcommand:check"`
+	Validate *manifests `arg:"subcommand:validate"`
+	Schema   *struct{}  `arg:"subcommand:schema" help:"Print JSON Schema of manifests"`
 }
 
 type manifests struct {
-	Manifests []string `arg:"positional" help:"Paths to JSON manifests"`
+	Manifests []string          `arg:"positional" help:"Paths to JSON, YAML or TOML manifests"`
+	Vars      map[string]string `arg:"--var,separate" help:"Set manifest variable for ${var:key}, e.g. --var key=value"`
 }
 
 type cmdBuild struct {
@@ -24,6 +33,8 @@ type cmdBuild struct {
 
 type cmdOther struct {
 	manifests
+	soyweb.FlagsDryRun
+	soyweb.FlagsSites
 }
 
 func main() {
@@ -34,8 +45,26 @@ func main() {
 }
 
 func run(c *cli) {
+	if c.Check != nil {
+		check(c.Check)
+		return
+	}
+	if c.Validate != nil {
+		validate(c.Validate)
+		return
+	}
+	if c.Schema != nil {
+		schema, err := soyweb.ManifestSchema()
+		if err != nil {
+			panic(err.Error())
+		}
+		os.Stdout.Write(schema)
+		return
+	}
+
 	var (
 		manifests []string
+		vars      map[string]string
 		flags     soyweb.FlagsV2
 		stages    soyweb.Stage
 	)
@@ -43,10 +72,11 @@ func run(c *cli) {
 	stages = soyweb.StageAll
 	switch {
 	case c.Build != nil:
-		manifests, flags = c.Build.Manifests, c.Build.FlagsV2
+		manifests, vars, flags = c.Build.Manifests, c.Build.Vars, c.Build.FlagsV2
 
 	case c.Copy != nil:
-		manifests = c.Copy.Manifests
+		manifests, vars, flags.FlagsDryRun = c.Copy.Manifests, c.Copy.Vars, c.Copy.FlagsDryRun
+		flags.FlagsSites = c.Copy.FlagsSites
 		stages = soyweb.StageCopy
 
 	case c.Clean != nil:
@@ -54,7 +84,8 @@ func run(c *cli) {
 		fallthrough
 
 	case c.CleanUp != nil:
-		manifests = c.CleanUp.Manifests
+		manifests, vars, flags.FlagsDryRun = c.CleanUp.Manifests, c.CleanUp.Vars, c.CleanUp.FlagsDryRun
+		flags.FlagsSites = c.CleanUp.FlagsSites
 		stages = soyweb.StageCleanUp
 	}
 
@@ -62,13 +93,109 @@ func run(c *cli) {
 		manifests = []string{"./manifest.json"}
 	}
 
+	reports := make(map[string]soyweb.Reports)
+	failed := false
 	for i := range manifests {
 		manifest := manifests[i]
-		m, err := soyweb.NewManifest(manifest)
+		m, err := soyweb.NewManifestVars(manifest, vars)
+		if err != nil {
+			failed = true
+			if !flags.ContinueOnError {
+				ssg.Fprintln(os.Stderr, err.Error())
+				break
+			}
+			ssg.Fprintf(os.Stderr, "%s: failed to load manifest\n", manifest)
+			soyweb.FprintSummary(os.Stderr, err)
+			continue
+		}
+
+		reports[manifest], err = soyweb.ApplyManifestReports(m, flags, stages)
+		if err == nil {
+			continue
+		}
+		failed = true
+		if !flags.ContinueOnError {
+			ssg.Fprintln(os.Stderr, err.Error())
+			break
+		}
+		ssg.Fprintf(os.Stderr, "%s: some sites failed\n", manifest)
+		soyweb.FprintSummary(os.Stderr, err)
+	}
+
+	if flags.Report != "" {
+		err := writeReport(flags.Report, reports)
 		if err != nil {
 			panic(err.Error())
 		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
 
-		soyweb.ApplyManifestV2(m, flags, stages)
+// check prints broken links in outputs of all sites in manifests,
+// and exits with status 1 if there is any
+func check(c *manifests) {
+	manifests := c.Manifests
+	if len(manifests) == 0 {
+		manifests = []string{"./manifest.json"}
+	}
+
+	found := false
+	for _, manifest := range manifests {
+		m, err := soyweb.NewManifestVars(manifest, c.Vars)
+		if err != nil {
+			panic(err.Error())
+		}
+		for _, key := range m.Keys() {
+			site := m[key]
+			broken, err := site.Check()
+			if err != nil {
+				panic(fmt.Sprintf("site '%s': %s", key, err.Error()))
+			}
+			ssg.FprintBrokenLinks(os.Stdout, broken)
+			found = found || len(broken) != 0
+		}
+	}
+	if found {
+		os.Exit(1)
+	}
+}
+
+// validate strictly validates manifests without building,
+// printing all errors found and exiting with status 1 if there is any
+func validate(c *manifests) {
+	manifests := c.Manifests
+	if len(manifests) == 0 {
+		manifests = []string{"./manifest.json"}
+	}
+
+	found := false
+	for _, manifest := range manifests {
+		err := soyweb.ValidateManifestVars(manifest, c.Vars)
+		if err != nil {
+			ssg.Fprintln(os.Stdout, err.Error())
+			found = true
+			continue
+		}
+		ssg.Fprintf(os.Stdout, "%s: ok\n", manifest)
+	}
+	if found {
+		os.Exit(1)
+	}
+}
+
+// writeReport writes reports of all manifests as JSON to path,
+// or to stdout if path is "-"
+func writeReport(path string, reports map[string]soyweb.Reports) error {
+	b, err := json.MarshalIndent(reports, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if path == "-" {
+		_, err = os.Stdout.Write(b)
+		return err
 	}
+	return os.WriteFile(path, b, 0644)
 }
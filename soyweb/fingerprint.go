@@ -0,0 +1,374 @@
+package soyweb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/soyart/ssg/ssg-go"
+)
+
+const (
+	FingerprintManifestDefault = "assets.json"
+	FingerprintLengthDefault   = 8
+)
+
+// FingerprintExtensionsDefault are extensions of assets fingerprinted by default.
+// .ico is left out, since browsers look for /favicon.ico by name.
+var FingerprintExtensionsDefault = []string{
+	".css", ".js",
+	".png", ".jpg", ".jpeg", ".gif", ".svg", ".webp", ".avif",
+	".woff", ".woff2", ".ttf", ".otf", ".eot",
+}
+
+var (
+	reCssUrl    = regexp.MustCompile(`url\(\s*(?:"([^"]*)"|'([^']*)'|([^)'"\s]*))\s*\)`)
+	reCssImport = regexp.MustCompile(`@import\s+(?:"([^"]*)"|'([^']*)')`)
+)
+
+// Fingerprint configures asset fingerprinting, which renames assets
+// in build outputs to names with hashes of their content, e.g. style.css
+// to style.3f9a1c2b.css, and rewrites references to the assets
+// in HTML and CSS outputs.
+type Fingerprint struct {
+	Extensions []string `json:"extensions"` // Extensions of assets, defaults to FingerprintExtensionsDefault
+	Manifest   string   `json:"manifest"`   // Path of JSON manifest of renamed assets under dst, defaults to FingerprintManifestDefault
+	Length     int      `json:"length"`     // Length of hex hashes in names, defaults to FingerprintLengthDefault
+}
+
+// fingerprinter renames assets of a build, see Fingerprint
+type fingerprinter struct {
+	Fingerprint
+	dst string
+	url string
+
+	assets  map[string]*ssg.OutputFile // Assets by target
+	renamed map[string]string          // Maps asset targets to fingerprinted targets
+	visit   ssg.Set                    // CSS assets being renamed, to detect import cycles
+}
+
+func (f *Fingerprint) validate() error {
+	if f.Length < 0 || f.Length > sha256.Size*2 {
+		return fmt.Errorf("bad fingerprint length %d", f.Length)
+	}
+	if f.Manifest != "" && (filepath.IsAbs(f.Manifest) || strings.HasPrefix(filepath.Clean(f.Manifest), "..")) {
+		return fmt.Errorf("fingerprint manifest '%s' is outside of dst", f.Manifest)
+	}
+	for _, ext := range f.Extensions {
+		if !strings.HasPrefix(ext, ".") {
+			return fmt.Errorf("bad fingerprint extension '%s'", ext)
+		}
+	}
+	return nil
+}
+
+// HookFingerprint returns an [ssg.HookOutputs] that fingerprints assets
+// in outputs of s, and adds the JSON manifest of renamed assets to the outputs
+func HookFingerprint(s *ssg.Ssg, opts Fingerprint) ssg.HookOutputs {
+	if len(opts.Extensions) == 0 {
+		opts.Extensions = FingerprintExtensionsDefault
+	}
+	if opts.Manifest == "" {
+		opts.Manifest = FingerprintManifestDefault
+	}
+	if opts.Length == 0 {
+		opts.Length = FingerprintLengthDefault
+	}
+
+	return func(outputs []ssg.OutputFile) ([]ssg.OutputFile, error) {
+		f := &fingerprinter{
+			Fingerprint: opts,
+			dst:         s.Dst,
+			url:         strings.TrimSuffix(s.Url, "/"),
+			assets:      make(map[string]*ssg.OutputFile),
+			renamed:     make(map[string]string),
+			visit:       make(ssg.Set),
+		}
+		return f.fingerprint(outputs)
+	}
+}
+
+func (f *fingerprinter) fingerprint(outputs []ssg.OutputFile) ([]ssg.OutputFile, error) {
+	for i := range outputs {
+		o := &outputs[i]
+		if f.isAsset(o) {
+			f.assets[o.Target()] = o
+		}
+	}
+	for target := range f.assets {
+		_, err := f.rename(target)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]ssg.OutputFile, 0, len(outputs)+1)
+	for i := range outputs {
+		o := &outputs[i]
+		switch {
+		case f.assets[o.Target()] != nil:
+			result = append(result, *f.assets[o.Target()])
+			continue
+
+		case isHtml(o.Target()) && o.Link() == "" && o.Redirect() == "":
+			data, err := f.rewriteHtml(o.Target(), o.Data())
+			if err != nil {
+				return nil, fmt.Errorf("failed to rewrite '%s': %w", o.Target(), err)
+			}
+			result = append(result, ssg.Output(o.Target(), o.Originator(), data, o.Perm()))
+			continue
+		}
+		result = append(result, *o)
+	}
+
+	manifest := make(map[string]string)
+	for target, renamed := range f.renamed {
+		manifest[relUrl(f.dst, target)] = relUrl(f.dst, renamed)
+	}
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	b = append(b, '\n')
+	return append(result, ssg.Output(filepath.Join(f.dst, f.Manifest), "", b, 0644)), nil
+}
+
+// rename renames asset at target, replacing f.assets[target] with the renamed output.
+// CSS assets are renamed after their references are rewritten, so that their hashes
+// change with the assets they reference.
+func (f *fingerprinter) rename(target string) (string, error) {
+	renamed, ok := f.renamed[target]
+	if ok {
+		return renamed, nil
+	}
+	if f.visit.Insert(target) {
+		return "", fmt.Errorf("import cycle in css '%s'", target)
+	}
+
+	o := f.assets[target]
+	data := o.Data()
+	if filepath.Ext(target) == ".css" {
+		var err error
+		data, err = f.rewriteCss(target, data)
+		if err != nil {
+			return "", fmt.Errorf("failed to rewrite '%s': %w", target, err)
+		}
+	}
+
+	hash := sha256.Sum256(data)
+	ext := filepath.Ext(target)
+	renamed = strings.TrimSuffix(target, ext) + "." + hex.EncodeToString(hash[:])[:f.Length] + ext
+
+	f.renamed[target] = renamed
+	renamedOutput := ssg.Output(renamed, o.Originator(), data, o.Perm())
+	f.assets[target] = &renamedOutput
+	return renamed, nil
+}
+
+// ref returns reference ref in file at target rewritten to the fingerprinted asset,
+// or ref itself if it does not reference an asset in the build
+func (f *fingerprinter) ref(target, ref string) (string, error) {
+	asset, u, err := resolveRef(f.dst, f.url, target, ref)
+	if err != nil {
+		return "", err
+	}
+	if asset == "" || f.assets[asset] == nil {
+		return ref, nil
+	}
+	renamed, err := f.rename(asset)
+	if err != nil {
+		return "", err
+	}
+
+	// Only the base name changes, so we keep the rest of ref as it is
+	base := path.Base(u.Path)
+	i := strings.LastIndex(ref, base)
+	if i == -1 {
+		return ref, nil // Escaped names
+	}
+	return ref[:i] + filepath.Base(renamed) + ref[i+len(base):], nil
+}
+
+// rewriteCss rewrites url() and @import references in CSS at target
+func (f *fingerprinter) rewriteCss(target string, css []byte) ([]byte, error) {
+	var err error
+	replace := func(re *regexp.Regexp) {
+		css = re.ReplaceAllFunc(css, func(match []byte) []byte {
+			if err != nil {
+				return match
+			}
+			sub := re.FindSubmatchIndex(match)
+			for g := 2; g < len(sub); g += 2 {
+				if sub[g] == -1 || sub[g] == sub[g+1] {
+					continue
+				}
+				var ref string
+				ref, err = f.ref(target, string(match[sub[g]:sub[g+1]]))
+				if err != nil {
+					return match
+				}
+				return append(append(bytes.Clone(match[:sub[g]]), ref...), match[sub[g+1]:]...)
+			}
+			return match
+		})
+	}
+	replace(reCssUrl)
+	replace(reCssImport)
+	return css, err
+}
+
+// rewriteHtml rewrites href, src and srcset attributes, and url()
+// in style attributes and elements, in HTML at target
+func (f *fingerprinter) rewriteHtml(target string, b []byte) ([]byte, error) {
+	out := bytes.NewBuffer(make([]byte, 0, len(b)))
+	z := html.NewTokenizer(bytes.NewReader(b))
+	style := false
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if errors.Is(z.Err(), io.EOF) {
+				return out.Bytes(), nil
+			}
+			return nil, z.Err()
+		}
+		raw := bytes.Clone(z.Raw())
+
+		switch tt {
+		case html.TextToken:
+			if style {
+				css, err := f.rewriteCss(target, raw)
+				if err != nil {
+					return nil, err
+				}
+				raw = css
+			}
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			if string(name) == "style" {
+				style = false
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			style = tt == html.StartTagToken && t.Data == "style"
+			changed := false
+			for i := range t.Attr {
+				a := &t.Attr[i]
+				var v string
+				var err error
+				switch a.Key {
+				case "href", "src":
+					v, err = f.ref(target, a.Val)
+				case "srcset":
+					v, err = f.srcset(target, a.Val)
+				case "style":
+					var css []byte
+					css, err = f.rewriteCss(target, []byte(a.Val))
+					v = string(css)
+				default:
+					continue
+				}
+				if err != nil {
+					return nil, err
+				}
+				if v != a.Val {
+					a.Val, changed = v, true
+				}
+			}
+			if changed {
+				raw = []byte(t.String())
+			}
+		}
+		out.Write(raw)
+	}
+}
+
+// srcset rewrites image candidates in srcset
+func (f *fingerprinter) srcset(target, srcset string) (string, error) {
+	changed := false
+	candidates := strings.Split(srcset, ",")
+	for i, c := range candidates {
+		fields := strings.Fields(c)
+		if len(fields) == 0 {
+			continue
+		}
+		ref, err := f.ref(target, fields[0])
+		if err != nil {
+			return "", err
+		}
+		if ref != fields[0] {
+			fields[0], changed = ref, true
+		}
+		candidates[i] = strings.Join(fields, " ")
+	}
+	if !changed {
+		return srcset, nil
+	}
+	return strings.Join(candidates, ", "), nil
+}
+
+func (f *fingerprinter) isAsset(o *ssg.OutputFile) bool {
+	if o.Link() != "" || o.Redirect() != "" {
+		return false
+	}
+	ext := strings.ToLower(filepath.Ext(o.Target()))
+	for _, e := range f.Extensions {
+		if ext == strings.ToLower(e) {
+			return true
+		}
+	}
+	return false
+}
+
+// relUrl returns target relative to dst as URL path
+func relUrl(dst, target string) string {
+	rel, err := filepath.Rel(dst, target)
+	if err != nil {
+		return target
+	}
+	return filepath.ToSlash(rel)
+}
+
+// resolveRef returns the target under dst referenced by ref in file at target,
+// and the parsed ref. Links to the site url are treated as root-relative.
+// The returned target is empty if ref does not link to a file on the site.
+func resolveRef(dst, siteUrl, target, ref string) (string, *url.URL, error) {
+	link := ref
+	if siteUrl != "" && strings.HasPrefix(link, siteUrl+"/") {
+		link = strings.TrimPrefix(link, siteUrl)
+	}
+	u, err := url.Parse(link)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Opaque != "" || u.Path == "" {
+		return "", nil, nil
+	}
+
+	p := u.Path
+	if !strings.HasPrefix(p, "/") {
+		rel, err := filepath.Rel(dst, filepath.Dir(target))
+		if err != nil {
+			return "", nil, err
+		}
+		p = path.Join("/", filepath.ToSlash(rel), p)
+	}
+	return filepath.Join(dst, filepath.FromSlash(path.Clean(p))), u, nil
+}
+
+func isHtml(path string) bool {
+	switch filepath.Ext(path) {
+	case ".html", ".htm":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package soyweb_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/soyart/ssg/soyweb"
+)
+
+func TestFingerprint(t *testing.T) {
+	root := t.TempDir()
+	src, dst := filepath.Join(root, "src"), filepath.Join(root, "dst")
+	files := map[string]string{
+		"_header.html":    `<html><head><link rel="stylesheet" href="/css/style.css"></head><body>`,
+		"_footer.html":    `</body></html>`,
+		"index.md":        "# Home",
+		"page.html":       `<img src="img/logo.svg" srcset="img/logo.svg 1x"><script src="https://fingerprint.example/js/app.js?v=1"></script><div style="background: url('img/bg.png')"></div>`,
+		"css/style.css":   `@import 'fonts.css'; body { background: url("../img/bg.png"); }`,
+		"css/fonts.css":   `@font-face { src: url(../fonts/a.woff2) format("woff2"); }`,
+		"img/bg.png":      "png",
+		"img/logo.svg":    "<svg></svg>",
+		"fonts/a.woff2":   "woff2",
+		"js/app.js":       "console.log('app')",
+		"favicon.ico":     "ico",
+		"robots.txt":      "User-agent: *",
+		"blog/index.html": `<a href="../page.html">Page</a>`,
+	}
+
+	build := func(t *testing.T) map[string]string {
+		for path, content := range files {
+			path = filepath.Join(src, path)
+			err := os.MkdirAll(filepath.Dir(path), 0755)
+			if err != nil {
+				panic(err)
+			}
+			err = os.WriteFile(path, []byte(content), 0644)
+			if err != nil {
+				panic(err)
+			}
+		}
+		err := os.RemoveAll(dst)
+		if err != nil {
+			panic(err)
+		}
+
+		manifestJSON := fmt.Sprintf(`{
+			"fingerprint": {
+				"url": "https://fingerprint.example",
+				"src": "%s",
+				"dst": "%s",
+				"fingerprint": {}
+			}
+		}`, src, dst)
+		var m Manifest
+		err = json.Unmarshal([]byte(manifestJSON), &m)
+		if err != nil {
+			t.Fatalf("failed to parse JSON: %v", err)
+		}
+		_, err = ApplyManifestReports(m, FlagsV2{}, StageBuild)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		b, err := os.ReadFile(filepath.Join(dst, FingerprintManifestDefault))
+		if err != nil {
+			t.Fatalf("missing fingerprint manifest: %v", err)
+		}
+		var renamed map[string]string
+		err = json.Unmarshal(b, &renamed)
+		if err != nil {
+			t.Fatalf("bad fingerprint manifest: %v", err)
+		}
+		return renamed
+	}
+
+	read := func(t *testing.T, path string) string {
+		b, err := os.ReadFile(filepath.Join(dst, path))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return string(b)
+	}
+
+	renamed := build(t)
+	assets := []string{"css/style.css", "css/fonts.css", "img/bg.png", "img/logo.svg", "fonts/a.woff2", "js/app.js"}
+	if len(renamed) != len(assets) {
+		t.Fatalf("unexpected fingerprint manifest: %+v", renamed)
+	}
+	for _, asset := range assets {
+		r := renamed[asset]
+		ext := filepath.Ext(asset)
+		if !strings.HasPrefix(r, strings.TrimSuffix(asset, ext)+".") || !strings.HasSuffix(r, ext) || len(r) != len(asset)+9 {
+			t.Fatalf("unexpected fingerprinted name for %s: '%s'", asset, r)
+		}
+		_, err := os.Stat(filepath.Join(dst, r))
+		if err != nil {
+			t.Fatalf("missing fingerprinted %s: %v", r, err)
+		}
+		_, err = os.Stat(filepath.Join(dst, asset))
+		if !os.IsNotExist(err) {
+			t.Fatalf("unexpected original asset %s: %v", asset, err)
+		}
+	}
+	for _, path := range []string{"favicon.ico", "robots.txt"} {
+		_, err := os.Stat(filepath.Join(dst, path))
+		if err != nil {
+			t.Fatalf("missing %s: %v", path, err)
+		}
+	}
+
+	base := func(asset string) string { return filepath.Base(renamed[asset]) }
+	expected := map[string][]string{
+		"index.html": {`href="/css/` + base("css/style.css") + `"`},
+		"page.html": {
+			`src="img/` + base("img/logo.svg") + `"`,
+			`srcset="img/` + base("img/logo.svg") + ` 1x"`,
+			`src="https://fingerprint.example/js/` + base("js/app.js") + `?v=1"`,
+			`url(&#39;img/` + base("img/bg.png") + `&#39;)`,
+		},
+		renamed["css/style.css"]: {`@import '` + base("css/fonts.css") + `'`, `url("../img/` + base("img/bg.png") + `")`},
+		renamed["css/fonts.css"]: {`url(../fonts/` + base("fonts/a.woff2") + `)`},
+		"blog/index.html":        {`<a href="../page.html">Page</a>`},
+	}
+	for path, contents := range expected {
+		data := read(t, path)
+		for _, content := range contents {
+			if !strings.Contains(data, content) {
+				t.Fatalf("missing '%s' in %s:\n%s", content, path, data)
+			}
+		}
+	}
+
+	// Changes in fonts cascade to CSS importing them
+	files["fonts/a.woff2"] = "woff2 changed"
+	changed := build(t)
+	for _, asset := range []string{"fonts/a.woff2", "css/fonts.css", "css/style.css"} {
+		if changed[asset] == renamed[asset] {
+			t.Fatalf("expecting fingerprint of %s to change", asset)
+		}
+	}
+	if changed["img/bg.png"] != renamed["img/bg.png"] {
+		t.Fatal("unexpected fingerprint change of img/bg.png")
+	}
+
+	for _, bad := range []string{`{"length": 100}`, `{"manifest": "../assets.json"}`, `{"extensions": ["css"]}`} {
+		var m Manifest
+		err := json.Unmarshal([]byte(fmt.Sprintf(`{"bad": {"url": "https://fingerprint.example", "src": "%s", "dst": "%s", "fingerprint": %s}}`, src, dst, bad)), &m)
+		if err == nil {
+			t.Fatalf("expecting error from bad fingerprint %s", bad)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package soyweb
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+
 	"github.com/soyart/ssg/ssg-go"
 )
 
@@ -18,6 +22,41 @@ type FlagsV2 struct {
 	MinifyCss          bool `arg:"--min-css" help:"Minify CSS files"`
 	MinifyJs           bool `arg:"--min-js" help:"Minify Javascript files"`
 	MinifyJson         bool `arg:"--min-json" help:"Minify JSON files"`
+
+	NoMinifyHtmlGenerate bool `arg:"--no-min-html" help:"Do not minify converted HTML outputs, even if enabled in manifest"`
+	NoMinifyHtmlCopy     bool `arg:"--no-min-html-copy" help:"Do not minify copied HTML, even if enabled in manifest"`
+	NoMinifyCss          bool `arg:"--no-min-css" help:"Do not minify CSS files, even if enabled in manifest"`
+	NoMinifyJs           bool `arg:"--no-min-js" help:"Do not minify Javascript files, even if enabled in manifest"`
+	NoMinifyJson         bool `arg:"--no-min-json" help:"Do not minify JSON files, even if enabled in manifest"`
+
+	Markdown []string `arg:"--markdown,separate" help:"Enable or disable Markdown option of all sites, e.g. smartypants=false, overriding manifest (repeatable)"`
+
+	Report  string `arg:"--report" help:"Write JSON build report to file ('-' for stdout)"`
+	Jobs    int    `arg:"-j,--jobs" help:"Copy and build up to N independent sites concurrently"`
+	Writers uint   `arg:"--writers" help:"Write outputs of each site with N concurrent writers, overriding writers in manifest"`
+
+	ContinueOnError bool `arg:"--continue-on-error" help:"Apply all sites even if some fail, and print a summary of failures"`
+
+	FlagsDryRun
+	FlagsSites
+}
+
+// FlagsDryRun represents CLI arguments for previewing changes
+// to be made by soyweb stages without writing anything.
+//
+// Each stage is planned against files currently on disk, not against
+// results of earlier stages. For example, files to be removed by cleanup
+// may also be reported as unchanged by copy.
+type FlagsDryRun struct {
+	DryRun bool `arg:"--dry-run" help:"Print changes to be made without writing. Each stage is planned against files on disk, not against results of earlier stages"`
+	Diff   bool `arg:"--diff" help:"With --dry-run, also print unified diffs of modified HTML outputs"`
+}
+
+// FlagsSites represents CLI arguments for selecting sites to apply
+// by their site keys. See [Manifest.Select].
+type FlagsSites struct {
+	Sites        []string `arg:"--site,separate" help:"Only apply sites with keys matching glob pattern (repeatable)"`
+	ExcludeSites []string `arg:"--exclude-site,separate" help:"Do not apply sites with keys matching glob pattern (repeatable)"`
 }
 
 type FlagsNoMinify struct {
@@ -42,6 +81,53 @@ func (f FlagsV2) Stage() Stage {
 	return s
 }
 
+// site returns f resolved with build options of site s.
+// Minify flags enable minifiers in addition to those of s,
+// while --no-min-* flags disable minifiers enabled by either.
+// Non-zero writers override writers of s.
+func (f FlagsV2) site(s Site) FlagsV2 {
+	f.MinifyHtmlGenerate = (f.MinifyHtmlGenerate || s.Minify.Html) && !f.NoMinifyHtmlGenerate
+	f.MinifyHtmlCopy = (f.MinifyHtmlCopy || s.Minify.HtmlCopy) && !f.NoMinifyHtmlCopy
+	f.MinifyCss = (f.MinifyCss || s.Minify.Css) && !f.NoMinifyCss
+	f.MinifyJs = (f.MinifyJs || s.Minify.Js) && !f.NoMinifyJs
+	f.MinifyJson = (f.MinifyJson || s.Minify.Json) && !f.NoMinifyJson
+	if f.Writers == 0 {
+		f.Writers = s.Writers
+	}
+	return f
+}
+
+// markdown returns Markdown options m of a site, which may be nil,
+// with options set by --markdown flags, e.g. smartypants=false,
+// or hard-line-break which is short for hard-line-break=true
+func (f FlagsV2) markdown(m *Markdown) (*Markdown, error) {
+	if len(f.Markdown) == 0 {
+		return m, nil
+	}
+	result := new(Markdown)
+	if m != nil {
+		*result = *m
+	}
+	options := result.fields()
+	for _, flag := range f.Markdown {
+		key, value, ok := strings.Cut(flag, "=")
+		enable := true
+		if ok {
+			var err error
+			enable, err = strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("bad markdown flag '%s': %w", flag, err)
+			}
+		}
+		option, ok := options[key]
+		if !ok {
+			return nil, fmt.Errorf("unknown markdown option '%s'", key)
+		}
+		*option = &enable
+	}
+	return result, nil
+}
+
 func (f FlagsV2) Hooks() []ssg.Hook {
 	return filterNilHooks(
 		f.hookMinify(),
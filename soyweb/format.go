@@ -0,0 +1,400 @@
+package soyweb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ManifestFormatJson = "json"
+	ManifestFormatYaml = "yaml"
+	ManifestFormatToml = "toml"
+)
+
+var reYamlLine = regexp.MustCompile(`line (\d+)`)
+
+// ManifestFormat returns format of manifest file by its extension:
+// .yaml and .yml files are YAML, .toml files are TOML, and others are JSON.
+func ManifestFormat(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		return ManifestFormatYaml
+	case ".toml":
+		return ManifestFormatToml
+	}
+	return ManifestFormatJson
+}
+
+// parseNodeFile parses manifest data b read from filename into nodes,
+// detecting the format with ManifestFormat. Errors are *ManifestError.
+func parseNodeFile(filename string, b []byte) (*node, error) {
+	var (
+		root *node
+		err  error
+	)
+	switch ManifestFormat(filename) {
+	case ManifestFormatYaml:
+		root, err = parseYaml(b)
+	case ManifestFormatToml:
+		root, err = parseToml(b)
+	default:
+		root, err = parseNode(b)
+		if err != nil {
+			return nil, positionError(filename, b, err)
+		}
+	}
+	if err != nil {
+		var errManifest *ManifestError
+		if errors.As(err, &errManifest) {
+			errManifest.File = filename
+			return nil, errManifest
+		}
+		return nil, &ManifestError{File: filename, Err: err}
+	}
+	return root, nil
+}
+
+// parseYaml parses YAML data b into nodes, resolving aliases and merge keys
+func parseYaml(b []byte) (*node, error) {
+	var doc yaml.Node
+	err := yaml.Unmarshal(b, &doc)
+	if err != nil {
+		line := 0
+		match := reYamlLine.FindStringSubmatch(err.Error())
+		if match != nil {
+			line, _ = strconv.Atoi(match[1])
+		}
+		return nil, &ManifestError{Line: line, Err: err}
+	}
+	if len(doc.Content) == 0 {
+		return &node{line: 1, column: 1, path: "$", object: true, fields: map[string]*node{}}, nil
+	}
+	return yamlNode(doc.Content[0], "$")
+}
+
+func yamlNode(y *yaml.Node, path string) (*node, error) {
+	n := &node{line: y.Line, column: y.Column, path: path}
+	switch y.Kind {
+	case yaml.AliasNode:
+		aliased, err := yamlNode(y.Alias, path)
+		if err != nil {
+			return nil, err
+		}
+		aliased.line, aliased.column = y.Line, y.Column
+		return aliased, nil
+
+	case yaml.MappingNode:
+		n.object = true
+		n.fields = make(map[string]*node)
+		var merges []*node
+		for i := 0; i+1 < len(y.Content); i += 2 {
+			k, v := y.Content[i], y.Content[i+1]
+			if k.Kind != yaml.ScalarNode {
+				return nil, &ManifestError{Path: path, Line: k.Line, Column: k.Column, Err: errors.New("non-scalar key")}
+			}
+			if k.ShortTag() == "!!merge" {
+				merged, err := yamlNode(v, path)
+				if err != nil {
+					return nil, err
+				}
+				merges = append(merges, merged)
+				continue
+			}
+			if n.fields[k.Value] != nil {
+				return nil, &ManifestError{Path: path + pathKey(k.Value), Line: k.Line, Column: k.Column, Err: fmt.Errorf("duplicate key '%s'", k.Value)}
+			}
+			field, err := yamlNode(v, path+pathKey(k.Value))
+			if err != nil {
+				return nil, err
+			}
+			field.line, field.column = k.Line, k.Column
+			n.set(k.Value, field)
+		}
+		// Explicit keys override merged keys
+		for _, merged := range merges {
+			sources := []*node{merged}
+			if merged.items != nil {
+				sources = merged.items
+			}
+			for _, source := range sources {
+				if !source.object {
+					return nil, &ManifestError{Path: path, Line: source.line, Column: source.column, Err: errors.New("merging non-mapping")}
+				}
+				for _, key := range source.keys {
+					if n.fields[key] == nil {
+						n.set(key, source.fields[key].rebase(path+pathKey(key)))
+					}
+				}
+			}
+		}
+
+	case yaml.SequenceNode:
+		n.items = make([]*node, len(y.Content))
+		for i := range y.Content {
+			item, err := yamlNode(y.Content[i], fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			n.items[i] = item
+		}
+
+	case yaml.ScalarNode:
+		var err error
+		switch y.ShortTag() {
+		case "!!null":
+			n.value = nil
+		case "!!bool":
+			var v bool
+			err = y.Decode(&v)
+			n.value = v
+		case "!!int":
+			var v int64
+			err = y.Decode(&v)
+			n.value = json.Number(strconv.FormatInt(v, 10))
+		case "!!float":
+			var v float64
+			err = y.Decode(&v)
+			n.value, err = number(v, err)
+		default:
+			n.value = y.Value
+		}
+		if err != nil {
+			return nil, &ManifestError{Path: path, Line: y.Line, Column: y.Column, Err: err}
+		}
+
+	default:
+		return nil, &ManifestError{Path: path, Line: y.Line, Column: y.Column, Err: fmt.Errorf("unexpected yaml node kind %d", y.Kind)}
+	}
+	return n, nil
+}
+
+// parseToml parses TOML data b into nodes
+func parseToml(b []byte) (*node, error) {
+	// The decoder checks TOML semantics, e.g. redefined tables,
+	// so that we can simply build nodes from valid documents.
+	var v any
+	err := toml.Unmarshal(b, &v)
+	if err != nil {
+		var errDecode *toml.DecodeError
+		if errors.As(err, &errDecode) {
+			line, column := errDecode.Position()
+			return nil, &ManifestError{Line: line, Column: column, Err: err}
+		}
+		// Errors of redefined keys have no positions, which nodes may find
+		_, errNodes := tomlNodes(b)
+		if errNodes != nil {
+			return nil, errNodes
+		}
+		return nil, err
+	}
+	return tomlNodes(b)
+}
+
+func tomlNodes(b []byte) (*node, error) {
+	t := &tomlParser{}
+	t.p.Reset(b)
+	root := &node{line: 1, column: 1, path: "$", object: true, fields: map[string]*node{}}
+	current := root
+	var err error
+	for t.p.NextExpression() {
+		e := t.p.Expression()
+		switch e.Kind {
+		case unstable.KeyValue:
+			err = t.keyValue(current, e)
+		case unstable.Table:
+			current, err = t.table(root, e.Key(), false)
+		case unstable.ArrayTable:
+			current, err = t.table(root, e.Key(), true)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if t.p.Error() != nil {
+		return nil, t.p.Error()
+	}
+	return root, nil
+}
+
+type tomlParser struct {
+	p unstable.Parser
+}
+
+// table returns the table at key, creating it if needed.
+// For array tables, a new table is appended to the array at key.
+func (t *tomlParser) table(root *node, key unstable.Iterator, array bool) (*node, error) {
+	current := root
+	for key.Next() {
+		k := key.Node()
+		name := string(k.Data)
+		line, column := t.position(k, current)
+		next := current.fields[name]
+		if next == nil {
+			next = &node{line: line, column: column, path: current.path + pathKey(name)}
+			if array && key.IsLast() {
+				next.items = []*node{}
+			} else {
+				next.object, next.fields = true, map[string]*node{}
+			}
+			current.set(name, next)
+		}
+		if array && key.IsLast() {
+			table := &node{
+				line:   line,
+				column: column,
+				path:   fmt.Sprintf("%s[%d]", next.path, len(next.items)),
+				object: true,
+				fields: map[string]*node{},
+			}
+			next.items = append(next.items, table)
+			return table, nil
+		}
+		// Keys of array tables refer to their last tables
+		if next.items != nil && len(next.items) != 0 {
+			next = next.items[len(next.items)-1]
+		}
+		current = next
+	}
+	return current, nil
+}
+
+// keyValue sets value of key-value expression e in table
+func (t *tomlParser) keyValue(table *node, e *unstable.Node) error {
+	key := e.Key()
+	current := table
+	for key.Next() {
+		k := key.Node()
+		name := string(k.Data)
+		line, column := t.position(k, current)
+		if !key.IsLast() {
+			next := current.fields[name]
+			if next == nil {
+				next = &node{line: line, column: column, path: current.path + pathKey(name), object: true, fields: map[string]*node{}}
+				current.set(name, next)
+			}
+			current = next
+			continue
+		}
+		if current.fields[name] != nil {
+			return &ManifestError{Path: current.path + pathKey(name), Line: line, Column: column, Err: fmt.Errorf("duplicate key '%s'", name)}
+		}
+		value, err := t.value(e.Value(), current.path+pathKey(name), line, column)
+		if err != nil {
+			return err
+		}
+		// Errors about keys point to the keys
+		value.line, value.column = line, column
+		current.set(name, value)
+	}
+	return nil
+}
+
+func (t *tomlParser) value(v *unstable.Node, path string, line, column int) (*node, error) {
+	if v.Raw.Length != 0 {
+		shape := t.p.Shape(v.Raw)
+		line, column = shape.Start.Line, shape.Start.Column
+	}
+	n := &node{line: line, column: column, path: path}
+	data := string(v.Data)
+
+	var err error
+	switch v.Kind {
+	case unstable.Bool:
+		n.value = data == "true"
+
+	case unstable.Integer:
+		var i int64
+		i, err = strconv.ParseInt(strings.ReplaceAll(data, "_", ""), 0, 64)
+		n.value = json.Number(strconv.FormatInt(i, 10))
+
+	case unstable.Float:
+		var f float64
+		f, err = strconv.ParseFloat(strings.ReplaceAll(data, "_", ""), 64)
+		n.value, err = number(f, err)
+
+	case unstable.Array:
+		n.items = []*node{}
+		children := v.Children()
+		for i := 0; children.Next(); i++ {
+			item, err := t.value(children.Node(), fmt.Sprintf("%s[%d]", path, i), line, column)
+			if err != nil {
+				return nil, err
+			}
+			n.items = append(n.items, item)
+		}
+
+	case unstable.InlineTable:
+		n.object, n.fields = true, map[string]*node{}
+		children := v.Children()
+		for children.Next() {
+			err := t.keyValue(n, children.Node())
+			if err != nil {
+				return nil, err
+			}
+		}
+
+	default:
+		n.value = data // Strings, dates and times
+	}
+	if err != nil {
+		return nil, &ManifestError{Path: path, Line: line, Column: column, Err: err}
+	}
+	return n, nil
+}
+
+// position returns position of TOML node k, or that of parent if unknown
+func (t *tomlParser) position(k *unstable.Node, parent *node) (int, int) {
+	if k.Raw.Length == 0 {
+		return parent.line, parent.column
+	}
+	shape := t.p.Shape(k.Raw)
+	return shape.Start.Line, shape.Start.Column
+}
+
+// set sets field key of object n to child
+func (n *node) set(key string, child *node) {
+	if n.fields[key] == nil {
+		n.keys = append(n.keys, key)
+	}
+	n.fields[key] = child
+}
+
+// rebase returns a copy of n and its children with paths under path
+func (n *node) rebase(path string) *node {
+	c := *n
+	c.path = path
+	if n.object {
+		c.fields = make(map[string]*node, len(n.fields))
+		for _, key := range n.keys {
+			c.fields[key] = n.fields[key].rebase(path + pathKey(key))
+		}
+	}
+	if n.items != nil {
+		c.items = make([]*node, len(n.items))
+		for i := range n.items {
+			c.items[i] = n.items[i].rebase(fmt.Sprintf("%s[%d]", path, i))
+		}
+	}
+	return &c
+}
+
+// number returns f as json.Number, or an error if f is not a JSON number
+func number(f float64, err error) (json.Number, error) {
+	if err != nil {
+		return "", err
+	}
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return "", fmt.Errorf("unsupported number %v", f)
+	}
+	return json.Number(strconv.FormatFloat(f, 'f', -1, 64)), nil
+}
@@ -0,0 +1,191 @@
+package soyweb_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/soyart/ssg/soyweb"
+)
+
+func TestManifestFormats(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(root, name)
+		err := os.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			panic(err)
+		}
+		return path
+	}
+
+	manifests := map[string]string{
+		"manifest.json": `{
+	"johndoe.com": {
+		"src": "johndoe.com/src",
+		"dst": "johndoe.com/dst",
+		"cleanup": true,
+		"dotfiles": [".well-known"],
+		"copies": {
+			"./assets/some.txt": "johndoe.com/src/some-txt.txt",
+			"./assets/some": {"target": "johndoe.com/src/drop", "force": true},
+			"./assets/style.css": [
+				{"target": "johndoe.com/src/style.css", "force": true},
+				"johndoe.com/src/style-copy.css"
+			]
+		},
+		"replaces": {
+			"replace-me-0": "replaced-text-0",
+			"replace-me-1": {"text": "replaced-text-1", "count": 3}
+		},
+		"images": {"widths": [480, 960], "webp": true}
+	}
+}`,
+		"manifest.yaml": `# Comments are allowed
+johndoe.com:
+  src: johndoe.com/src
+  dst: johndoe.com/dst
+  cleanup: true
+  dotfiles: [.well-known]
+  copies:
+    ./assets/some.txt: johndoe.com/src/some-txt.txt
+    ./assets/some:
+      target: johndoe.com/src/drop
+      force: true
+    ./assets/style.css:
+      - target: johndoe.com/src/style.css
+        force: true
+      - johndoe.com/src/style-copy.css
+  replaces:
+    replace-me-0: replaced-text-0
+    replace-me-1:
+      text: replaced-text-1
+      count: 3
+  images:
+    widths: [480, 960]
+    webp: true
+`,
+		"manifest.toml": `# Comments are allowed
+["johndoe.com"]
+src = "johndoe.com/src"
+dst = "johndoe.com/dst"
+cleanup = true
+dotfiles = [".well-known"]
+images = { widths = [480, 960], webp = true }
+
+["johndoe.com".copies]
+"./assets/some.txt" = "johndoe.com/src/some-txt.txt"
+"./assets/some" = { target = "johndoe.com/src/drop", force = true }
+"./assets/style.css" = [
+  { target = "johndoe.com/src/style.css", force = true },
+  "johndoe.com/src/style-copy.css",
+]
+
+["johndoe.com".replaces]
+replace-me-0 = "replaced-text-0"
+replace-me-1 = { text = "replaced-text-1", count = 3 }
+`,
+	}
+
+	sites := make(map[string]Site)
+	for name, content := range manifests {
+		m, err := NewManifest(write(name, content))
+		if err != nil {
+			t.Fatalf("[%s] unexpected error: %v", name, err)
+		}
+		site, ok := m["johndoe.com"]
+		if !ok || len(m) != 1 {
+			t.Fatalf("[%s] unexpected sites: %+v", name, m)
+		}
+		sites[name] = site
+	}
+
+	expected := sites["manifest.json"]
+	if len(expected.Copies["./assets/style.css"]) != 2 || expected.Replaces["replace-me-1"].Count != 3 {
+		t.Fatalf("unexpected site from JSON: %+v", expected)
+	}
+	for name, site := range sites {
+		if site.Src() != expected.Src() || site.Dst() != expected.Dst() || site.CleanUp != expected.CleanUp {
+			t.Fatalf("[%s] unexpected site: %+v", name, site)
+		}
+		for field, values := range map[string][2]any{
+			"copies":   {site.Copies, expected.Copies},
+			"replaces": {site.Replaces, expected.Replaces},
+			"dotfiles": {site.Dotfiles, expected.Dotfiles},
+			"images":   {site.Images, expected.Images},
+		} {
+			if !reflect.DeepEqual(values[0], values[1]) {
+				t.Fatalf("[%s] unexpected %s: %+v, expecting %+v", name, field, values[0], values[1])
+			}
+		}
+	}
+
+	// YAML anchors and merge keys share options between sites
+	m, err := NewManifest(write("anchors.yml", `a: &a
+  src: a/src
+  dst: a/dst
+  dotfiles: [.well-known]
+b:
+  <<: *a
+  dst: b/dst
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b := m["b"]; b.Src() != "a/src" || b.Dst() != "b/dst" || len(b.Dotfiles) != 1 {
+		t.Fatalf("unexpected merged site: %+v", b)
+	}
+
+	type testCase struct {
+		name     string
+		manifest string
+		expected string
+	}
+	tests := []testCase{
+		{
+			name:     "bad.yaml",
+			manifest: "site:\n  src: src\n  dst: dst\n  generate_index: true\n",
+			expected: `:4:3: $.site.generate_index: unknown key 'generate_index', did you mean 'generate-index'?`,
+		},
+		{
+			name:     "bad.yaml",
+			manifest: "site:\n  src: src\n  dst: dst\n  images:\n    widths: [big]\n",
+			expected: `:5:14: $.site.images.widths[0]: expecting integer, got string`,
+		},
+		{
+			name:     "bad.yaml",
+			manifest: "site:\n  src: src\n  src: dst\n",
+			expected: `:3:3: $.site.src: duplicate key 'src'`,
+		},
+		{
+			name:     "bad.yml",
+			manifest: "site:\n  src: src\n dst: dst\n",
+			expected: `:2: yaml: line 2:`,
+		},
+		{
+			name:     "bad.toml",
+			manifest: "[site]\nsrc = \"src\"\ndst = \"dst\"\ncleanup = \"yes\"\n",
+			expected: `:4:1: $.site.cleanup: expecting boolean, got string`,
+		},
+		{
+			name:     "bad.toml",
+			manifest: "[site]\nsrc = \"src\"\n\n[site.images]\nqualty = 80\n",
+			expected: `:1:2: $.site: missing required key 'dst'`,
+		},
+		{
+			name:     "bad.toml",
+			manifest: "[site]\nsrc = \"src\"\nsrc = \"dst\"\n",
+			expected: `:3:1: $.site.src: duplicate key 'src'`,
+		},
+	}
+	for i := range tests {
+		tc := &tests[i]
+		path := write(tc.name, tc.manifest)
+		_, err := NewManifest(path)
+		if err == nil || !strings.HasPrefix(err.Error(), path+tc.expected) {
+			t.Fatalf("[%d] unexpected error: expecting '%s', got '%v'", i, path+tc.expected, err)
+		}
+	}
+}
@@ -5,14 +5,20 @@ go 1.23.0
 toolchain go1.24.1
 
 require (
+	github.com/HugoSmits86/nativewebp v1.2.1
 	github.com/alexflint/go-arg v1.5.1
+	github.com/gomarkdown/markdown v0.0.0-20250311123330-531bef5e742b
+	github.com/pelletier/go-toml/v2 v2.2.4
 	github.com/soyart/ssg/ssg-go v0.0.0-20250413194932-6d1399cdb005
 	github.com/tdewolff/minify/v2 v2.23.1
+	golang.org/x/image v0.25.0
+	golang.org/x/net v0.35.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/alexflint/go-scalar v1.2.0 // indirect
-	github.com/gomarkdown/markdown v0.0.0-20250311123330-531bef5e742b // indirect
+	github.com/andybalholm/brotli v1.2.0 // indirect
 	github.com/sabhiram/go-gitignore v0.0.0-20210923224102-525f6e181f06 // indirect
 	github.com/tdewolff/parse/v2 v2.7.23 // indirect
 )
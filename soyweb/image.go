@@ -0,0 +1,366 @@
+package soyweb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+	"golang.org/x/net/html"
+
+	"github.com/soyart/ssg/ssg-go"
+)
+
+const (
+	ImageSizesDefault   = "100vw"
+	ImageQualityDefault = 85
+)
+
+// Images configures the soyweb image pipeline, which generates resized
+// and WebP variants of JPEG and PNG images under src, and rewrites
+// <img> elements in generated HTML to use the variants.
+type Images struct {
+	Widths  []int  `json:"widths"`  // Widths of resized variants, only those narrower than the images are generated
+	Sizes   string `json:"sizes"`   // Value of sizes attributes, defaults to ImageSizesDefault
+	WebP    bool   `json:"webp"`    // Also generate WebP variants, served with <picture>
+	Quality int    `json:"quality"` // JPEG quality, defaults to ImageQualityDefault
+	Cache   string `json:"cache"`   // Directory of processed images cached by hash, defaults to user cache directory
+}
+
+// images implements the image pipeline for a site
+type images struct {
+	Images
+	ssg     *ssg.Ssg
+	dryRun  bool                    // Processed images are not cached when dry-running
+	configs map[string]image.Config // Image dimensions by source path
+}
+
+func newImages(s *ssg.Ssg, opts Images, dryRun bool) *images {
+	if opts.Sizes == "" {
+		opts.Sizes = ImageSizesDefault
+	}
+	if opts.Quality == 0 {
+		opts.Quality = ImageQualityDefault
+	}
+	if opts.Cache == "" {
+		dir, err := os.UserCacheDir()
+		if err == nil {
+			opts.Cache = filepath.Join(dir, "soyweb", "images")
+		}
+	}
+	widths := make([]int, len(opts.Widths))
+	copy(widths, opts.Widths)
+	sort.Ints(widths)
+	opts.Widths = widths
+
+	return &images{
+		Images:  opts,
+		ssg:     s,
+		dryRun:  dryRun,
+		configs: make(map[string]image.Config),
+	}
+}
+
+func (i *Images) validate() error {
+	for _, w := range i.Widths {
+		if w <= 0 {
+			return fmt.Errorf("bad image width %d", w)
+		}
+	}
+	if i.Quality < 0 || i.Quality > 100 {
+		return fmt.Errorf("bad image quality %d", i.Quality)
+	}
+	return nil
+}
+
+// pipeline outputs variants of images. Images themselves are left to ssg-go.
+func (i *images) pipeline(path string, data []byte, d fs.DirEntry) (string, []byte, fs.DirEntry, error) {
+	if !isImage(path) {
+		return path, data, d, nil
+	}
+	info, err := d.Info()
+	if err != nil {
+		return path, data, d, err
+	}
+	config, err := i.config(path, data)
+	if err != nil {
+		return path, data, d, fmt.Errorf("bad image '%s': %w", path, err)
+	}
+
+	target, err := filepath.Rel(i.ssg.Src, path)
+	if err != nil {
+		return path, data, d, err
+	}
+	target = filepath.Join(i.ssg.Dst, target)
+
+	hash := sha256.Sum256(data)
+	var img image.Image
+	decode := func() (image.Image, error) {
+		if img != nil {
+			return img, nil
+		}
+		img, _, err = image.Decode(bytes.NewReader(data))
+		return img, err
+	}
+
+	var outputs []ssg.OutputFile
+	for _, w := range i.widths(config.Width) {
+		exts := []string{filepath.Ext(path)}
+		if w == config.Width {
+			exts = nil // The image itself is copied by ssg-go
+		}
+		if i.WebP {
+			exts = append(exts, ".webp")
+		}
+		for _, ext := range exts {
+			b, err := i.variant(hash[:], w, ext, config, decode)
+			if err != nil {
+				return path, data, d, fmt.Errorf("failed to process image '%s': %w", path, err)
+			}
+			outputs = append(outputs, ssg.Output(variantPath(target, w, config.Width, ext), path, b, info.Mode().Perm()))
+		}
+	}
+	i.ssg.Outputs().Add(outputs...)
+	return path, data, d, nil
+}
+
+// hookOutputs rewrites <img> elements in HTML generated from Markdown
+// to use srcset, sizes, width and height attributes. Relative image links
+// are resolved in the directory of each page output.
+func (i *images) hookOutputs(outputs []ssg.OutputFile) ([]ssg.OutputFile, error) {
+	for j := range outputs {
+		o := &outputs[j]
+		if !isHtml(o.Target()) || filepath.Ext(o.Originator()) != ".md" || o.Link() != "" || o.Redirect() != "" {
+			continue
+		}
+		dir, err := filepath.Rel(i.ssg.Dst, filepath.Dir(o.Target()))
+		if err != nil {
+			return nil, err
+		}
+		data, err := i.rewriteHtml(filepath.ToSlash(dir), o.Data())
+		if err != nil {
+			return nil, fmt.Errorf("failed to rewrite '%s': %w", o.Target(), err)
+		}
+		outputs[j] = ssg.Output(o.Target(), o.Originator(), data, o.Perm())
+	}
+	return outputs, nil
+}
+
+// rewriteHtml rewrites <img> elements in HTML page b,
+// whose output is in directory dir relative to dst
+func (i *images) rewriteHtml(dir string, b []byte) ([]byte, error) {
+	out := bytes.NewBuffer(make([]byte, 0, len(b)))
+	z := html.NewTokenizer(bytes.NewReader(b))
+	pictures := 0
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if errors.Is(z.Err(), io.EOF) {
+				return out.Bytes(), nil
+			}
+			return nil, z.Err()
+		}
+		raw := bytes.Clone(z.Raw())
+		t := z.Token()
+		switch {
+		case t.Data == "picture" && tt == html.StartTagToken:
+			pictures++
+		case t.Data == "picture" && tt == html.EndTagToken:
+			pictures--
+		case t.Data == "img" && pictures == 0 && (tt == html.StartTagToken || tt == html.SelfClosingTagToken):
+			img, err := i.rewrite(dir, t)
+			if err != nil {
+				return nil, err
+			}
+			if img != "" {
+				out.WriteString(img)
+				continue
+			}
+		}
+		out.Write(raw)
+	}
+}
+
+// rewrite returns the rewritten img element, or an empty string
+// if img does not link to a local image or already has srcset
+func (i *images) rewrite(dir string, img html.Token) (string, error) {
+	src := attr(img, "src")
+	if src == "" || attr(img, "srcset") != "" {
+		return "", nil
+	}
+	u, err := url.Parse(src)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Opaque != "" || !isImage(u.Path) {
+		return "", nil
+	}
+	p := u.Path
+	if !strings.HasPrefix(p, "/") {
+		p = path.Join("/", dir, p)
+	}
+	source := filepath.Join(i.ssg.Src, filepath.FromSlash(path.Clean(p)))
+	data, err := os.ReadFile(source)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", nil // Not an image from src, e.g. copied by soyweb
+		}
+		return "", err
+	}
+	config, err := i.config(source, data)
+	if err != nil {
+		return "", fmt.Errorf("bad image '%s': %w", source, err)
+	}
+
+	srcset := func(ext string) string {
+		var set []string
+		for _, w := range i.widths(config.Width) {
+			variant := *u
+			variant.Path, variant.RawPath = variantPath(u.Path, w, config.Width, ext), ""
+			set = append(set, fmt.Sprintf("%s %dw", variant.String(), w))
+		}
+		return strings.Join(set, ", ")
+	}
+
+	attrs := img.Attr
+	attrs = append(attrs,
+		html.Attribute{Key: "srcset", Val: srcset(path.Ext(u.Path))},
+		html.Attribute{Key: "sizes", Val: i.Sizes},
+	)
+	if attr(img, "width") == "" && attr(img, "height") == "" {
+		attrs = append(attrs,
+			html.Attribute{Key: "width", Val: strconv.Itoa(config.Width)},
+			html.Attribute{Key: "height", Val: strconv.Itoa(config.Height)},
+		)
+	}
+
+	b := bytes.NewBuffer(nil)
+	if i.WebP {
+		ssg.Fprintf(b, `<picture><source type="image/webp" srcset="%s" sizes="%s">`,
+			html.EscapeString(srcset(".webp")),
+			html.EscapeString(i.Sizes),
+		)
+	}
+	b.WriteString("<img")
+	for _, a := range attrs {
+		ssg.Fprintf(b, ` %s="%s"`, a.Key, html.EscapeString(a.Val))
+	}
+	b.WriteString(">")
+	if i.WebP {
+		b.WriteString("</picture>")
+	}
+	return b.String(), nil
+}
+
+// widths returns widths of variants of image with width w, including w
+func (i *images) widths(w int) []int {
+	var widths []int
+	for _, width := range i.Widths {
+		if width < w {
+			widths = append(widths, width)
+		}
+	}
+	return append(widths, w)
+}
+
+// config returns dimensions of image at path with data
+func (i *images) config(path string, data []byte) (image.Config, error) {
+	config, ok := i.configs[path]
+	if ok {
+		return config, nil
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return image.Config{}, err
+	}
+	i.configs[path] = config
+	return config, nil
+}
+
+// variant returns variant of image with hash resized to width w and
+// encoded as ext, reading it from the cache if it was processed before
+func (i *images) variant(hash []byte, w int, ext string, config image.Config, decode func() (image.Image, error)) ([]byte, error) {
+	key := sha256.Sum256(fmt.Appendf(hash, ":%d:%s:%d", w, ext, i.Quality))
+	cached := ""
+	if i.Cache != "" {
+		cached = filepath.Join(i.Cache, hex.EncodeToString(key[:])+ext)
+		b, err := os.ReadFile(cached)
+		if err == nil {
+			return b, nil
+		}
+	}
+
+	img, err := decode()
+	if err != nil {
+		return nil, err
+	}
+	if w != config.Width {
+		h := max(config.Height*w/config.Width, 1)
+		resized := image.NewRGBA(image.Rect(0, 0, w, h))
+		draw.CatmullRom.Scale(resized, resized.Bounds(), img, img.Bounds(), draw.Over, nil)
+		img = resized
+	}
+
+	b := bytes.NewBuffer(nil)
+	switch strings.ToLower(ext) {
+	case ".webp":
+		err = nativewebp.Encode(b, img, nil)
+	case ".png":
+		err = png.Encode(b, img)
+	default:
+		err = jpeg.Encode(b, img, &jpeg.Options{Quality: i.Quality})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cached != "" && !i.dryRun {
+		err = os.MkdirAll(i.Cache, 0755)
+		if err == nil {
+			err = os.WriteFile(cached, b.Bytes(), 0644)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to cache image: %w", err)
+		}
+	}
+	return b.Bytes(), nil
+}
+
+// variantPath returns path of variant of image at p with width w,
+// e.g. img/photo-480w.jpg, or img/photo.webp if w is the image width
+func variantPath(p string, w, width int, ext string) string {
+	base := strings.TrimSuffix(p, path.Ext(p))
+	if w == width {
+		return base + ext
+	}
+	return fmt.Sprintf("%s-%dw%s", base, w, ext)
+}
+
+func isImage(p string) bool {
+	switch strings.ToLower(path.Ext(p)) {
+	case ".jpg", ".jpeg", ".png":
+		return true
+	}
+	return false
+}
+
+func attr(t html.Token, key string) string {
+	for _, a := range t.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,137 @@
+package soyweb_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/soyart/ssg/soyweb"
+)
+
+func TestImages(t *testing.T) {
+	root := t.TempDir()
+	src, dst, cache := filepath.Join(root, "src"), filepath.Join(root, "dst"), filepath.Join(root, "cache")
+	err := os.MkdirAll(filepath.Join(src, "img"), 0755)
+	if err != nil {
+		panic(err)
+	}
+	err = os.WriteFile(filepath.Join(src, "index.md"), []byte("# Photos\n\n![Photo](img/photo.png)\n"), 0644)
+	if err != nil {
+		panic(err)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	for x := 0; x < 200; x++ {
+		for y := 0; y < 100; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	f, err := os.Create(filepath.Join(src, "img", "photo.png"))
+	if err != nil {
+		panic(err)
+	}
+	err = png.Encode(f, img)
+	f.Close()
+	if err != nil {
+		panic(err)
+	}
+
+	manifestJSON := fmt.Sprintf(`{
+		"images": {
+			"url": "https://images.example",
+			"src": "%s",
+			"dst": "%s",
+			"images": {
+				"widths": [400, 50, 100],
+				"sizes": "(max-width: 200px) 100vw, 200px",
+				"webp": true,
+				"cache": "%s"
+			}
+		}
+	}`, src, dst, cache)
+
+	var m Manifest
+	err = json.Unmarshal([]byte(manifestJSON), &m)
+	if err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+
+	_, err = ApplyManifestReports(m, FlagsV2{FlagsDryRun: FlagsDryRun{DryRun: true}}, StageBuild)
+	if err != nil {
+		t.Fatalf("unexpected error from dry-run: %v", err)
+	}
+	for _, path := range []string{cache, dst} {
+		_, err = os.Stat(path)
+		if !os.IsNotExist(err) {
+			t.Fatalf("unexpected %s written by dry-run: %v", path, err)
+		}
+	}
+
+	for build := 0; build < 2; build++ {
+		_, err = ApplyManifestReports(m, FlagsV2{}, StageBuild)
+		if err != nil {
+			t.Fatalf("[build %d] unexpected error: %v", build, err)
+		}
+
+		for variant, width := range map[string]int{
+			"img/photo.png":       200,
+			"img/photo-50w.png":   50,
+			"img/photo-100w.png":  100,
+			"img/photo.webp":      200,
+			"img/photo-50w.webp":  50,
+			"img/photo-100w.webp": 100,
+		} {
+			f, err := os.Open(filepath.Join(dst, variant))
+			if err != nil {
+				t.Fatalf("[build %d] missing variant %s: %v", build, variant, err)
+			}
+			config, _, err := image.DecodeConfig(f)
+			f.Close()
+			if err != nil {
+				t.Fatalf("[build %d] bad variant %s: %v", build, variant, err)
+			}
+			if config.Width != width || config.Height != width/2 {
+				t.Fatalf("[build %d] unexpected dimensions of %s: %dx%d", build, variant, config.Width, config.Height)
+			}
+		}
+		_, err = os.Stat(filepath.Join(dst, "img", "photo-400w.png"))
+		if !os.IsNotExist(err) {
+			t.Fatalf("[build %d] unexpected variant wider than image: %v", build, err)
+		}
+
+		entries, err := os.ReadDir(cache)
+		if err != nil {
+			t.Fatalf("[build %d] unexpected error reading cache: %v", build, err)
+		}
+		if len(entries) != 5 {
+			t.Fatalf("[build %d] unexpected number of cached images: %d", build, len(entries))
+		}
+
+		b, err := os.ReadFile(filepath.Join(dst, "index.html"))
+		if err != nil {
+			t.Fatalf("[build %d] unexpected error: %v", build, err)
+		}
+		for _, expected := range []string{
+			`<picture><source type="image/webp" srcset="img/photo-50w.webp 50w, img/photo-100w.webp 100w, img/photo.webp 200w" sizes="(max-width: 200px) 100vw, 200px">`,
+			`srcset="img/photo-50w.png 50w, img/photo-100w.png 100w, img/photo.png 200w"`,
+			`width="200" height="100"`,
+			`</picture>`,
+		} {
+			if !strings.Contains(string(b), expected) {
+				t.Fatalf("[build %d] missing '%s' in index.html:\n%s", build, expected, b)
+			}
+		}
+	}
+
+	var site Site
+	err = json.Unmarshal([]byte(`{"src": "src", "dst": "dst", "images": {"widths": [0]}}`), &site)
+	if err == nil {
+		t.Fatal("expecting error from bad image width")
+	}
+}
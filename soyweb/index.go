@@ -38,7 +38,7 @@ func NewIndexGenerator(m IndexGeneratorMode) func(*ssg.Ssg) ssg.Pipeline {
 func IndexGenerator(s *ssg.Ssg) ssg.Pipeline {
 	return IndexGeneratorTemplate(
 		nil,
-		generatorDefault,
+		generatorDefault(s),
 	)(s)
 }
 
@@ -50,7 +50,7 @@ func IndexGeneratorReverse(s *ssg.Ssg) ssg.Pipeline {
 			reverseInPlace(entries)
 			return entries
 		},
-		generatorDefault,
+		generatorDefault(s),
 	)(s)
 }
 
@@ -73,7 +73,7 @@ func IndexGeneratorModTime(s *ssg.Ssg) ssg.Pipeline {
 			sort.Slice(entries, sortByModTime(entries))
 			return entries
 		},
-		generatorDefault,
+		generatorDefault(s),
 	)(s)
 }
 
@@ -115,7 +115,11 @@ func IndexGeneratorTemplate(
 			}
 
 			parent := filepath.Dir(path)
-			ssg.Fprintf(os.Stdout, "found index-generator marker: marker=\"%s\", parent=\"%s\"\n", path, parent)
+			if logger := s.Logger(); logger != nil {
+				logger.Info("found index-generator marker", "marker", path, "parent", parent)
+			} else {
+				ssg.Fprintf(os.Stdout, "found index-generator marker: marker=\"%s\", parent=\"%s\"\n", path, parent)
+			}
 
 			entries, err := os.ReadDir(parent)
 			if err != nil {
@@ -151,7 +155,32 @@ func IndexGeneratorTemplate(
 	}
 }
 
-// generatorDefault is a default index generator.
+// generatorDefault returns generateIndex with path strategy of s
+func generatorDefault(s *ssg.Ssg) func(
+	src string,
+	ignore func(path string) bool,
+	parent string,
+	siblings []fs.FileInfo,
+	template []byte,
+) (
+	string,
+	error,
+) {
+	return func(
+		src string,
+		ignore func(path string) bool,
+		parent string,
+		siblings []fs.FileInfo,
+		template []byte,
+	) (
+		string,
+		error,
+	) {
+		return generateIndex(s.PathStrategy(), src, ignore, parent, siblings, template)
+	}
+}
+
+// generateIndex is a default index generator.
 //
 // It generates 1 index.md for each _index.soyweb.
 // The default generator does accept a template, and will append its generated content
@@ -165,18 +194,22 @@ func IndexGeneratorTemplate(
 // each line composing of 2 components: a link title and the actual link path,
 // looking something like this: `[link-title](/actual/link)`.
 //
-// generatorDefault ensures that all links have titles, and will automatically
+// Links to Markdown entries honor the path strategy paths, e.g. with
+// ssg.PathsPretty, the link to ./entry1.md will be /entry1/ instead of /entry1.html.
+//
+// generateIndex ensures that all links have titles, and will automatically
 // select link titles based on these 2 steps:
 //
 // 1. From the entry filename or directory name.
 // For example, if there're 2 entries ./entry1.md and ./entry-2/index.md,
-// then generatorDefault will first assign "entry1" as link title for ./entry1.md,
+// then generateIndex will first assign "entry1" as link title for ./entry1.md,
 // while "entry-2" is used for ./entry-2/index.md.
 //
 // 2. From the entry's 1st h1 tag.
-// If your entry happens to have an h1 tag, generatorDefault will use those as link title.
+// If your entry happens to have an h1 tag, generateIndex will use those as link title.
 // Otherwise it just sticks with link title previously obtained from step 1.
-func generatorDefault(
+func generateIndex(
+	paths ssg.PathStrategy,
 	src string,
 	ignore func(path string) bool,
 	parent string,
@@ -199,6 +232,7 @@ func generatorDefault(
 
 		// Default is to use dir/filename as link title
 		linkTitle := sibName
+		var data []byte // Content of Markdown sibling
 
 		switch sibName {
 		case "index.html", "index.md":
@@ -263,14 +297,15 @@ func generatorDefault(
 			}
 
 		case sibExt == ".md":
-			title, err := extractTitle(sibPath)
+			var err error
+			data, err = ssg.ReadFile(sibPath)
 			if err != nil {
-				return "", err
+				return "", fmt.Errorf("failed to read article file %s for title extraction: %w", sibPath, err)
 			}
+			title := titleOf(data)
 			if len(title) != 0 {
 				linkTitle = string(title)
 			}
-			sibName = ssg.ChangeExt(sibName, ".md", ".html")
 		}
 
 		rel, err := filepath.Rel(src, parent)
@@ -278,8 +313,15 @@ func generatorDefault(
 			return "", err
 		}
 		link := filepath.Join(rel, sibName)
-		if sibIsDir {
+		switch {
+		case sibIsDir:
 			link += "/"
+		case sibExt == ".md":
+			// Markdown pages may have custom slugs or permalinks
+			link, err = paths.LinkOf(link, data)
+			if err != nil {
+				return "", err
+			}
 		}
 		ssg.Fprintf(output, "- [%s](/%s)\n\n", linkTitle, link)
 	}
@@ -297,9 +339,13 @@ func extractTitle(path string) ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to read article file %s for title extraction: %w", path, err)
 	}
+	return titleOf(data), nil
+}
+
+func titleOf(data []byte) []byte {
 	title := ssg.GetTitleFromTag(data)
 	if len(title) != 0 {
-		return title, nil
+		return title
 	}
-	return ssg.GetTitleFromH1(data), nil
+	return ssg.GetTitleFromH1(data)
 }
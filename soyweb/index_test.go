@@ -144,6 +144,51 @@ func TestGenerateIndex(t *testing.T) {
 	}
 }
 
+func TestGenerateIndexPretty(t *testing.T) {
+	src := "../testdata/myblog/src"
+	dst := "../testdata/myblog/dst-test-generate-index-pretty"
+	defer os.RemoveAll(dst)
+
+	err := ssg.Generate(src, dst, "TestTitle", "https://my.blog",
+		ssg.WithPipelines(IndexGenerator),
+		ssg.WithPathStrategy(ssg.PathsPretty),
+	)
+	if err != nil {
+		t.Fatalf("error during ssg generation: %v", err)
+	}
+
+	contains := map[string][]string{
+		"2022/index.html": {
+			`<li><p><a href="/2022/bar/">bar</a></p></li>`,
+			`<li><p><a href="/2022/foo/">Foo</a></p></li>`,
+		},
+		"2023/index.html": {
+			`<li><p><a href="/2023/baz/">Bazketball</a></p></li>`,
+			`<li><p><a href="/2023/recurse/">Recurse Index</a></p></li>`,
+		},
+	}
+	for index, entries := range contains {
+		content, err := os.ReadFile(filepath.Join(dst, index))
+		if err != nil {
+			t.Fatalf("failed to read back index %s: %v", index, err)
+		}
+		for i := range entries {
+			if !strings.Contains(string(content), entries[i]) {
+				t.Log("actual content:\n", string(content))
+				t.Fatalf("missing #%d entry '%s' in %s", i+1, entries[i], index)
+			}
+		}
+	}
+
+	for _, page := range []string{"2022/foo/index.html", "2023/baz/index.html"} {
+		assertFs(t, filepath.Join(dst, page), false)
+	}
+	_, err = os.Stat(filepath.Join(dst, "2022/foo.html"))
+	if !os.IsNotExist(err) {
+		t.Fatalf("unexpected ugly output 2022/foo.html")
+	}
+}
+
 func formatIndexPath(marker string) string {
 	marker = filepath.Dir(marker)
 	return filepath.Join(marker, "index.html")
@@ -0,0 +1,277 @@
+package soyweb
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	// manifestDefaultsKey is the top-level key for options applied to all sites
+	manifestDefaultsKey = "defaults"
+	// manifestIncludeKey is the top-level key for other manifests to merge
+	manifestIncludeKey = "include"
+	// manifestExtendsKey is the site key for the site to inherit options from
+	manifestExtendsKey = "extends"
+)
+
+// loader loads manifests into nodes, merging included manifests
+// and resolving defaults and extends of sites.
+//
+// Options of a site are taken in order of precedence from:
+//
+//  1. The site itself
+//  2. The site it extends, resolved recursively
+//  3. Defaults of the manifest defining the site, merged with
+//     defaults of its included manifests
+//
+// Objects are merged key by key, while other values are replaced.
+// Sites of included manifests are resolved in their own manifests,
+// and are replaced by sites with the same keys in including manifests.
+type loader struct {
+	vars  map[string]string // Variables for ${var:NAME}, see interpolate
+	stack []string          // Absolute paths of manifests being loaded
+}
+
+// manifestReserved reports whether top-level key of manifests is not a site
+func manifestReserved(key string) bool {
+	switch key {
+	case manifestSchemaKey, manifestDefaultsKey, manifestIncludeKey:
+		return true
+	}
+	return false
+}
+
+// load parses manifest data b read from filename into nodes,
+// with sites resolved. Includes are relative to filename.
+func (l *loader) load(filename string, b []byte) (*node, error) {
+	root, err := parseNodeFile(filename, b)
+	if err != nil {
+		return nil, err
+	}
+	root.setFile(filename)
+	// Interpolated before merging, so that values are interpolated once
+	err = interpolate(root, l.vars)
+	if err != nil {
+		return nil, err
+	}
+	if !root.object {
+		return root, nil // Reported by validator
+	}
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	l.stack = append(l.stack, abs)
+	defer func() { l.stack = l.stack[:len(l.stack)-1] }()
+
+	result := root.without(root.keys...)
+	var defaults *node
+	include := root.fields[manifestIncludeKey]
+	if include != nil {
+		includes, err := includes(include)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range includes {
+			included, err := l.include(filename, n)
+			if err != nil {
+				return nil, err
+			}
+			if !included.object {
+				return nil, included.errorf("expecting manifest object of sites, got %s", included.kind())
+			}
+			for _, key := range included.keys {
+				switch key {
+				case manifestDefaultsKey:
+					defaults = merge(defaults, included.fields[key])
+				case manifestSchemaKey, manifestIncludeKey:
+				default:
+					result.replace(key, included.fields[key])
+				}
+			}
+		}
+	}
+
+	var own []string
+	for _, key := range root.keys {
+		switch key {
+		case manifestDefaultsKey:
+			defaults = merge(defaults, root.fields[key])
+		case manifestSchemaKey, manifestIncludeKey:
+			result.replace(key, root.fields[key])
+		default:
+			result.replace(key, root.fields[key])
+			own = append(own, key)
+		}
+	}
+	if defaults != nil {
+		result.replace(manifestDefaultsKey, defaults)
+	}
+
+	r := &resolver{sites: result, own: own, resolved: make(map[string]*node)}
+	for _, key := range own {
+		site, err := r.resolve(key, []string{key})
+		if err != nil {
+			return nil, err
+		}
+		if defaults != nil && defaults.object && site.object {
+			site = merge(defaults, site)
+		}
+		result.fields[key] = site
+	}
+	return result, nil
+}
+
+// include loads manifest included by include node n of manifest filename
+func (l *loader) include(filename string, n *node) (*node, error) {
+	path := n.value.(string)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(filename), path)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, n.errorf("bad include '%s': %w", path, err)
+	}
+	i := slices.Index(l.stack, abs)
+	if i != -1 {
+		cycle := append(slices.Clone(l.stack[i:]), abs)
+		for j := range cycle {
+			cycle[j] = filepath.Base(cycle[j])
+		}
+		return nil, n.errorf("include cycle: %s", strings.Join(cycle, " -> "))
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, n.errorf("failed to read include '%s': %w", path, err)
+	}
+	return l.load(path, b)
+}
+
+// includes returns string nodes of include node n,
+// which is a string or an array of strings
+func includes(n *node) ([]*node, error) {
+	items := []*node{n}
+	if n.items != nil {
+		items = n.items
+	}
+	for _, item := range items {
+		if item.kind() != "string" {
+			return nil, item.errorf("expecting string or array of strings, got %s", item.kind())
+		}
+	}
+	return items, nil
+}
+
+// resolver resolves extends of sites defined in a manifest
+type resolver struct {
+	sites    *node
+	own      []string // Keys of sites defined in the manifest
+	resolved map[string]*node
+}
+
+// resolve returns site key merged with the site it extends.
+// chain is the keys of sites extending key, to detect cycles.
+func (r *resolver) resolve(key string, chain []string) (*node, error) {
+	site := r.sites.fields[key]
+	if resolved, ok := r.resolved[key]; ok {
+		return resolved, nil
+	}
+	if !slices.Contains(r.own, key) || !site.object {
+		return site, nil
+	}
+	extends := site.fields[manifestExtendsKey]
+	if extends == nil {
+		r.resolved[key] = site
+		return site, nil
+	}
+	base, ok := extends.value.(string)
+	if !ok {
+		return nil, extends.errorf("expecting string, got %s", extends.kind())
+	}
+	if slices.Contains(chain, base) {
+		return nil, extends.errorf("extends cycle: %s", strings.Join(append(chain, base), " -> "))
+	}
+	if r.sites.fields[base] == nil || manifestReserved(base) {
+		var keys []string
+		for _, k := range r.sites.keys {
+			if k != key && !manifestReserved(k) {
+				keys = append(keys, k)
+			}
+		}
+		if suggestion := suggest(base, keys); suggestion != "" {
+			return nil, extends.errorf("extends unknown site '%s', did you mean '%s'?", base, suggestion)
+		}
+		return nil, extends.errorf("extends unknown site '%s'", base)
+	}
+
+	resolved, err := r.resolve(base, append(slices.Clone(chain), base))
+	if err != nil {
+		return nil, err
+	}
+	resolved = merge(resolved, site.without(manifestExtendsKey))
+	r.resolved[key] = resolved
+	return resolved, nil
+}
+
+// merge returns over merged onto base. Objects are merged key by key,
+// with values of over taking precedence, while other values are replaced.
+// Merged nodes keep their positions, so errors point to where values are written.
+func merge(base, over *node) *node {
+	if base == nil || !base.object || !over.object {
+		return over
+	}
+	merged := over.without(over.keys...)
+	for _, key := range base.keys {
+		merged.set(key, base.fields[key])
+	}
+	for _, key := range over.keys {
+		merged.set(key, merge(merged.fields[key], over.fields[key]))
+	}
+	return merged
+}
+
+// without returns a shallow copy of object n without keys
+func (n *node) without(keys ...string) *node {
+	c := *n
+	c.keys, c.fields = nil, make(map[string]*node, len(n.fields))
+	for _, key := range n.keys {
+		if !slices.Contains(keys, key) {
+			c.set(key, n.fields[key])
+		}
+	}
+	return &c
+}
+
+// replace removes key from object n, and appends key with child
+func (n *node) replace(key string, child *node) {
+	if n.fields[key] != nil {
+		n.keys = slices.DeleteFunc(n.keys, func(k string) bool { return k == key })
+		delete(n.fields, key)
+	}
+	n.set(key, child)
+}
+
+// setFile sets file of n and its children
+func (n *node) setFile(file string) {
+	n.file = file
+	for _, key := range n.keys {
+		n.fields[key].setFile(file)
+	}
+	for _, item := range n.items {
+		item.setFile(file)
+	}
+}
+
+// errorf returns a *ManifestError located at n
+func (n *node) errorf(format string, args ...any) *ManifestError {
+	return &ManifestError{
+		File:   n.file,
+		Path:   n.path,
+		Line:   n.line,
+		Column: n.column,
+		Err:    fmt.Errorf(format, args...),
+	}
+}
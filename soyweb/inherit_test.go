@@ -0,0 +1,190 @@
+package soyweb_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/soyart/ssg/soyweb"
+)
+
+func TestManifestInheritance(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(root, name)
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			panic(err)
+		}
+		err = os.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			panic(err)
+		}
+		return path
+	}
+
+	write("shared/shared.yaml", `defaults:
+  generate-index: true
+  replaces:
+    "{{ a }}": shared-a
+shared:
+  src: shared/src
+  dst: shared/dst
+`)
+	manifest := write("manifest.json", `{
+	"include": "shared/shared.yaml",
+	"defaults": {
+		"cleanup": true,
+		"replaces": {"{{ b }}": "default-b"}
+	},
+	"base": {
+		"src": "base/src",
+		"dst": "base/dst",
+		"dotfiles": [".well-known"],
+		"replaces": {"{{ c }}": "base-c"}
+	},
+	"child": {
+		"extends": "base",
+		"dst": "child/dst",
+		"replaces": {"{{ a }}": "child-a"}
+	},
+	"grandchild": {
+		"extends": "child",
+		"dst": "grandchild/dst",
+		"cleanup": false,
+		"dotfiles": []
+	}
+}`)
+
+	m, err := NewManifest(manifest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	type expected struct {
+		src, dst      string
+		cleanup       bool
+		generateIndex bool
+		dotfiles      []string
+		replaces      map[string]string
+	}
+	tests := map[string]expected{
+		// Defaults of including manifests do not apply to included sites
+		"shared": {
+			src:           "shared/src",
+			dst:           "shared/dst",
+			generateIndex: true,
+			replaces:      map[string]string{"{{ a }}": "shared-a"},
+		},
+		"base": {
+			src:           "base/src",
+			dst:           "base/dst",
+			cleanup:       true,
+			generateIndex: true,
+			dotfiles:      []string{".well-known"},
+			replaces:      map[string]string{"{{ a }}": "shared-a", "{{ b }}": "default-b", "{{ c }}": "base-c"},
+		},
+		"child": {
+			src:           "base/src",
+			dst:           "child/dst",
+			cleanup:       true,
+			generateIndex: true,
+			dotfiles:      []string{".well-known"},
+			replaces:      map[string]string{"{{ a }}": "child-a", "{{ b }}": "default-b", "{{ c }}": "base-c"},
+		},
+		"grandchild": {
+			src:           "base/src",
+			dst:           "grandchild/dst",
+			cleanup:       false,
+			generateIndex: true,
+			dotfiles:      []string{},
+			replaces:      map[string]string{"{{ a }}": "child-a", "{{ b }}": "default-b", "{{ c }}": "base-c"},
+		},
+	}
+	if len(m) != len(tests) {
+		t.Fatalf("unexpected number of sites: expecting %d, got %d", len(tests), len(m))
+	}
+	for key, e := range tests {
+		site, ok := m[key]
+		if !ok {
+			t.Fatalf("missing site %s", key)
+		}
+		if site.Src() != e.src || site.Dst() != e.dst {
+			t.Fatalf("[%s] unexpected src/dst: %s %s", key, site.Src(), site.Dst())
+		}
+		if site.CleanUp != e.cleanup || site.GenerateIndex != e.generateIndex {
+			t.Fatalf("[%s] unexpected cleanup/generate-index: %v %v", key, site.CleanUp, site.GenerateIndex)
+		}
+		if !reflect.DeepEqual(site.Dotfiles, e.dotfiles) {
+			t.Fatalf("[%s] unexpected dotfiles: %v", key, site.Dotfiles)
+		}
+		replaces := make(map[string]string)
+		for text, target := range site.Replaces {
+			replaces[text] = target.Text
+		}
+		if !reflect.DeepEqual(replaces, e.replaces) {
+			t.Fatalf("[%s] unexpected replaces: %v", key, replaces)
+		}
+	}
+
+	write("cycle/a.json", `{"include": ["b.json"]}`)
+	write("cycle/b.yaml", `include: a.json`)
+	write("cycle/b.json", `{"include": "b.yaml"}`)
+
+	type testCase struct {
+		file     string
+		manifest string
+		expected []string
+	}
+	errTests := []testCase{
+		{
+			file:     "cycle/a.json",
+			expected: []string{"b.yaml:1:1: $.include: include cycle: a.json -> b.json -> b.yaml -> a.json"},
+		},
+		{
+			file:     "missing.json",
+			manifest: `{"include": ["shared/shared.yaml", "nope.json"]}`,
+			expected: []string{"missing.json:1:36: $.include[1]: failed to read include"},
+		},
+		{
+			file:     "extends-cycle.json",
+			manifest: `{"a": {"src": "a", "dst": "a", "extends": "c"}, "b": {"src": "b", "dst": "b", "extends": "a"}, "c": {"extends": "b"}}`,
+			expected: []string{"extends-cycle.json:1:79: $.b.extends: extends cycle: a -> c -> b -> a"},
+		},
+		{
+			file:     "extends-unknown.json",
+			manifest: `{"base": {"src": "a", "dst": "a"}, "child": {"extends": "bsae"}}`,
+			expected: []string{"extends-unknown.json:1:46: $.child.extends: extends unknown site 'bsae', did you mean 'base'?"},
+		},
+		{
+			file:     "defaults.json",
+			manifest: "{\n\"defaults\": {\"extends\": \"a\", \"clenaup\": true},\n\"a\": {\"src\": \"a\", \"dst\": \"a-dst\"},\n\"b\": {\"src\": \"b\", \"dst\": \"b-dst\"}\n}",
+			expected: []string{
+				"defaults.json:2:14: $.defaults.extends: extends is not allowed in defaults",
+				"defaults.json:2:30: $.defaults.clenaup: unknown key 'clenaup', did you mean 'cleanup'?",
+			},
+		},
+	}
+	for i := range errTests {
+		tc := &errTests[i]
+		path := filepath.Join(root, tc.file)
+		if tc.manifest != "" {
+			path = write(tc.file, tc.manifest)
+		}
+		_, err := NewManifest(path)
+		if err == nil {
+			t.Fatalf("[%d] unexpected nil error", i)
+		}
+		lines := strings.Split(err.Error(), "\n")
+		if len(lines) != len(tc.expected) {
+			t.Fatalf("[%d] unexpected number of errors: expecting %d, got %d:\n%v", i, len(tc.expected), len(lines), err)
+		}
+		for j := range lines {
+			if !strings.Contains(lines[j], tc.expected[j]) {
+				t.Fatalf("[%d] unexpected error: expecting '%s', got '%s'", i, tc.expected[j], lines[j])
+			}
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package soyweb
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/soyart/ssg/ssg-go"
+)
+
+// Integrity configures Subresource Integrity, which adds integrity attributes
+// with sha384 hashes of scripts and stylesheets to <script src> and
+// <link rel="stylesheet"> elements in HTML outputs.
+//
+// Hashes are computed from the outputs as they are written, i.e. after
+// minification and fingerprinting, so only assets built by ssg-go are hashed.
+type Integrity struct {
+	Paths []string `json:"paths"` // Glob patterns of assets relative to dst, e.g. js/*.js. Defaults to all assets
+}
+
+// integrity adds integrity attributes to outputs, see Integrity
+type integrity struct {
+	Integrity
+	dst string
+	url string
+
+	assets map[string]*ssg.OutputFile // Outputs by target
+	hashes map[string]string          // Integrity values by target
+}
+
+func (i *Integrity) validate() error {
+	for _, pattern := range i.Paths {
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			return fmt.Errorf("bad integrity path '%s': %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// HookIntegrity returns an [ssg.HookOutputs] that adds integrity attributes
+// to scripts and stylesheets linked from HTML outputs of s
+func HookIntegrity(s *ssg.Ssg, opts Integrity) ssg.HookOutputs {
+	return func(outputs []ssg.OutputFile) ([]ssg.OutputFile, error) {
+		i := &integrity{
+			Integrity: opts,
+			dst:       s.Dst,
+			url:       strings.TrimSuffix(s.Url, "/"),
+			assets:    make(map[string]*ssg.OutputFile),
+			hashes:    make(map[string]string),
+		}
+		for j := range outputs {
+			o := &outputs[j]
+			if o.Link() == "" && o.Redirect() == "" {
+				i.assets[o.Target()] = o
+			}
+		}
+
+		result := make([]ssg.OutputFile, len(outputs))
+		for j, o := range outputs {
+			result[j] = o
+			if !isHtml(o.Target()) || o.Link() != "" || o.Redirect() != "" {
+				continue
+			}
+			data, err := i.rewriteHtml(o.Target(), o.Data())
+			if err != nil {
+				return nil, fmt.Errorf("failed to add integrity to '%s': %w", o.Target(), err)
+			}
+			result[j] = ssg.Output(o.Target(), o.Originator(), data, o.Perm())
+		}
+		return result, nil
+	}
+}
+
+// rewriteHtml adds integrity attributes to elements
+// linking scripts and stylesheets in HTML at target
+func (i *integrity) rewriteHtml(target string, b []byte) ([]byte, error) {
+	out := bytes.NewBuffer(make([]byte, 0, len(b)))
+	z := html.NewTokenizer(bytes.NewReader(b))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if errors.Is(z.Err(), io.EOF) {
+				return out.Bytes(), nil
+			}
+			return nil, z.Err()
+		}
+		raw := bytes.Clone(z.Raw())
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			out.Write(raw)
+			continue
+		}
+
+		t := z.Token()
+		var ref string
+		switch {
+		case t.Data == "script":
+			ref = attr(t, "src")
+		case t.Data == "link" && isStylesheet(attr(t, "rel")):
+			ref = attr(t, "href")
+		}
+		if ref == "" || attr(t, "integrity") != "" {
+			out.Write(raw)
+			continue
+		}
+		hash, err := i.hash(target, ref)
+		if err != nil {
+			return nil, err
+		}
+		if hash == "" {
+			out.Write(raw)
+			continue
+		}
+		t.Attr = append(t.Attr, html.Attribute{Key: "integrity", Val: hash})
+		out.WriteString(t.String())
+	}
+}
+
+// hash returns integrity value of asset referenced by ref in file at target,
+// or an empty string if ref does not link to an allowed asset in the build
+func (i *integrity) hash(target, ref string) (string, error) {
+	asset, _, err := resolveRef(i.dst, i.url, target, ref)
+	if err != nil || asset == "" {
+		return "", err
+	}
+	hash, ok := i.hashes[asset]
+	if ok {
+		return hash, nil
+	}
+	o := i.assets[asset]
+	if o == nil || !i.allowed(asset) {
+		return "", nil
+	}
+	sum := sha512.Sum384(o.Data())
+	hash = "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+	i.hashes[asset] = hash
+	return hash, nil
+}
+
+func (i *integrity) allowed(asset string) bool {
+	if len(i.Paths) == 0 {
+		return true
+	}
+	rel := relUrl(i.dst, asset)
+	for _, pattern := range i.Paths {
+		ok, _ := path.Match(pattern, rel)
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func isStylesheet(rel string) bool {
+	for _, r := range strings.Fields(rel) {
+		if strings.EqualFold(r, "stylesheet") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package soyweb_test
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/soyart/ssg/soyweb"
+)
+
+func TestIntegrity(t *testing.T) {
+	root := t.TempDir()
+	src, dst := filepath.Join(root, "src"), filepath.Join(root, "dst")
+	files := map[string]string{
+		"_header.html": `<html><head><link rel="preload stylesheet" href="/css/style.css"><link rel="icon" href="/css/style.css"></head><body>`,
+		"_footer.html": `<script src="/js/app.js"></script><script src="https://cdn.example/lib.js"></script><script src="/js/pinned.js" integrity="sha384-pinned"></script></body></html>`,
+		"index.md":     "# Home",
+		"blog/a.md":    "# A",
+		"css/style.css": `body {
+  color: red;
+}
+`,
+		"js/app.js":    "console.log( 'app' );\n",
+		"js/pinned.js": "console.log('pinned');\n",
+	}
+	for path, content := range files {
+		path = filepath.Join(src, path)
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			panic(err)
+		}
+		err = os.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	build := func(t *testing.T, integrity string, flags FlagsV2) {
+		err := os.RemoveAll(dst)
+		if err != nil {
+			panic(err)
+		}
+		manifestJSON := fmt.Sprintf(`{
+			"integrity": {
+				"url": "https://integrity.example",
+				"src": "%s",
+				"dst": "%s",
+				"fingerprint": {},
+				"integrity": %s
+			}
+		}`, src, dst, integrity)
+		var m Manifest
+		err = json.Unmarshal([]byte(manifestJSON), &m)
+		if err != nil {
+			t.Fatalf("failed to parse JSON: %v", err)
+		}
+		_, err = ApplyManifestReports(m, flags, StageBuild)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// Hashes are computed from the final outputs
+	hash := func(t *testing.T, asset string) string {
+		b, err := os.ReadFile(filepath.Join(dst, FingerprintManifestDefault))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var renamed map[string]string
+		err = json.Unmarshal(b, &renamed)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		b, err = os.ReadFile(filepath.Join(dst, renamed[asset]))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		sum := sha512.Sum384(b)
+		return fmt.Sprintf(`integrity="sha384-%s"`, base64.StdEncoding.EncodeToString(sum[:]))
+	}
+
+	read := func(t *testing.T, path string) string {
+		b, err := os.ReadFile(filepath.Join(dst, path))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return string(b)
+	}
+
+	build(t, `{}`, FlagsV2{MinifyCss: true, MinifyJs: true})
+	css, js := hash(t, "css/style.css"), hash(t, "js/app.js")
+	for _, page := range []string{"index.html", "blog/a.html"} {
+		html := read(t, page)
+		if strings.Count(html, css) != 1 {
+			t.Fatalf("expecting 1 stylesheet integrity in %s:\n%s", page, html)
+		}
+		if !strings.Contains(html, js) {
+			t.Fatalf("missing script integrity in %s:\n%s", page, html)
+		}
+		if strings.Count(html, "integrity=") != 3 {
+			t.Fatalf("unexpected integrity attributes in %s:\n%s", page, html)
+		}
+		if !strings.Contains(html, `<script src="https://cdn.example/lib.js"></script>`) {
+			t.Fatalf("unexpected integrity of external script in %s:\n%s", page, html)
+		}
+		if !strings.Contains(html, `integrity="sha384-pinned"`) {
+			t.Fatalf("unexpected changed integrity in %s:\n%s", page, html)
+		}
+	}
+
+	build(t, `{"paths": ["css/*"]}`, FlagsV2{})
+	html := read(t, "index.html")
+	if !strings.Contains(html, hash(t, "css/style.css")) || strings.Count(html, "integrity=") != 2 {
+		t.Fatalf("unexpected integrity attributes with paths:\n%s", html)
+	}
+
+	var m Manifest
+	err := json.Unmarshal([]byte(fmt.Sprintf(`{"bad": {"src": "%s", "dst": "%s", "integrity": {"paths": ["["]}}}`, src, dst)), &m)
+	if err == nil {
+		t.Fatal("expecting error from bad integrity path")
+	}
+}
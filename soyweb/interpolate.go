@@ -0,0 +1,109 @@
+package soyweb
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	interpolateEnv = "env"
+	interpolateVar = "var"
+)
+
+// interpolate replaces ${env:NAME} with environment variable NAME,
+// and ${var:NAME} with variable NAME in vars, in string values of n and its children.
+// Object keys are not interpolated.
+func interpolate(n *node, vars map[string]string) error {
+	if s, ok := n.value.(string); ok {
+		interpolated, err := interpolateString(s, vars)
+		if err != nil {
+			return n.errorf("%w", err)
+		}
+		n.value = interpolated
+	}
+	for _, key := range n.keys {
+		err := interpolate(n.fields[key], vars)
+		if err != nil {
+			return err
+		}
+	}
+	for _, item := range n.items {
+		err := interpolate(item, vars)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// interpolateString interpolates s with vars like in shells:
+//
+//   - ${env:NAME} and ${var:NAME} are replaced with values of NAME,
+//     which must be defined
+//
+//   - ${env:NAME:-default} and ${var:NAME:-default} fall back to default
+//     if NAME is undefined or empty
+//
+//   - $${ is replaced with literal ${
+//
+// Other ${ are kept as they are, e.g. ${name} in JavaScript template strings.
+func interpolateString(s string, vars map[string]string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	b := strings.Builder{}
+	for i := 0; i < len(s); {
+		if strings.HasPrefix(s[i:], "$${") {
+			b.WriteString("${")
+			i += 3
+			continue
+		}
+		if !strings.HasPrefix(s[i:], "${"+interpolateEnv+":") && !strings.HasPrefix(s[i:], "${"+interpolateVar+":") {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+		end := strings.IndexByte(s[i:], '}')
+		if end == -1 {
+			return "", fmt.Errorf("unterminated interpolation '%s'", s[i:])
+		}
+		value, err := interpolateExpr(s[i+2:i+end], vars)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(value)
+		i += end + 1
+	}
+	return b.String(), nil
+}
+
+// interpolateExpr returns value of expression expr inside ${}
+func interpolateExpr(expr string, vars map[string]string) (string, error) {
+	kind, name, _ := strings.Cut(expr, ":")
+	name, fallback, hasFallback := strings.Cut(name, ":-")
+	if name == "" {
+		return "", fmt.Errorf("bad interpolation '${%s}', expecting ${%s:NAME}", expr, kind)
+	}
+
+	var (
+		value   string
+		defined bool
+	)
+	switch kind {
+	case interpolateEnv:
+		value, defined = os.LookupEnv(name)
+	case interpolateVar:
+		value, defined = vars[name]
+	}
+	if hasFallback && value == "" {
+		return fallback, nil
+	}
+	if !defined && kind == interpolateEnv {
+		return "", fmt.Errorf("undefined environment variable '%s'", name)
+	}
+	if !defined {
+		return "", fmt.Errorf("undefined variable '%s'", name)
+	}
+	return value, nil
+}
@@ -0,0 +1,93 @@
+package soyweb_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/soyart/ssg/soyweb"
+)
+
+func TestManifestInterpolation(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(root, name)
+		err := os.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			panic(err)
+		}
+		return path
+	}
+	t.Setenv("SOYWEB_TEST_URL", "https://staging.example.com")
+	t.Setenv("SOYWEB_TEST_EMPTY", "")
+
+	write("staging.yaml", `site:
+  src: src
+  dst: ${var:dst}
+  url: ${env:SOYWEB_TEST_URL}
+  copies:
+    ./assets: ${var:dst}/assets
+  replaces:
+    "${var:year}": "year ${var:year:-2024}, $${var:year} and $ alone"
+    "{{ default }}": "${env:SOYWEB_TEST_EMPTY:-fallback}${env:SOYWEB_TEST_UNDEFINED:-}"
+    "{{ greet }}": "hello ${name}, ${foo:bar} and ${ env:x }"
+`)
+	manifest := write("manifest.json", `{"include": "${var:env}.yaml"}`)
+
+	m, err := NewManifestVars(manifest, map[string]string{"env": "staging", "dst": "dist/staging"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	site := m["site"]
+	if site.Dst() != "dist/staging" {
+		t.Fatalf("unexpected dst: %s", site.Dst())
+	}
+	if target := site.Copies["./assets"]; len(target) != 1 || target[0].Target != "dist/staging/assets" {
+		t.Fatalf("unexpected copies: %+v", site.Copies)
+	}
+	// Keys are not interpolated
+	replace, ok := site.Replaces["${var:year}"]
+	if !ok || replace.Text != "year 2024, ${var:year} and $ alone" {
+		t.Fatalf("unexpected replaces: %+v", site.Replaces)
+	}
+	if replace := site.Replaces["{{ default }}"]; replace.Text != "fallback" {
+		t.Fatalf("unexpected replace with default: '%s'", replace.Text)
+	}
+	// Other ${ are not interpolated
+	if replace := site.Replaces["{{ greet }}"]; replace.Text != "hello ${name}, ${foo:bar} and ${ env:x }" {
+		t.Fatalf("unexpected replace with template string: '%s'", replace.Text)
+	}
+
+	type testCase struct {
+		manifest string
+		vars     map[string]string
+		expected string
+	}
+	tests := []testCase{
+		{
+			manifest: `{"site": {"src": "src", "dst": "${var:dst}"}}`,
+			expected: `:1:25: $.site.dst: undefined variable 'dst'`,
+		},
+		{
+			manifest: `{"site": {"src": "src", "dst": "dst", "url": "${env:SOYWEB_TEST_UNDEFINED}"}}`,
+			expected: `:1:39: $.site.url: undefined environment variable 'SOYWEB_TEST_UNDEFINED'`,
+		},
+		{
+			manifest: `{"site": {"src": "src", "dst": "${var:}"}}`,
+			expected: `:1:25: $.site.dst: bad interpolation '${var:}', expecting ${var:NAME}`,
+		},
+		{
+			manifest: `{"site": {"src": "src", "dst": "${var:dst"}}`,
+			vars:     map[string]string{"dst": "dst"},
+			expected: `:1:25: $.site.dst: unterminated interpolation '${var:dst'`,
+		},
+	}
+	for i := range tests {
+		tc := &tests[i]
+		path := write("bad.json", tc.manifest)
+		_, err := NewManifestVars(path, tc.vars)
+		if err == nil || err.Error() != path+tc.expected {
+			t.Fatalf("[%d] unexpected error: expecting '%s', got '%v'", i, path+tc.expected, err)
+		}
+	}
+}
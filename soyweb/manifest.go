@@ -1,14 +1,20 @@
 package soyweb
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"log/slog"
 	"os"
+	"path"
 	"path/filepath"
 	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
 
 	"github.com/soyart/ssg/ssg-go"
 )
@@ -30,26 +36,58 @@ const (
 
 type Manifest map[string]Site
 
+// Reports maps site keys to ssg-go reports of the site builds
+type Reports map[string]*ssg.Report
+
 type Site struct {
-	ssg ssg.Ssg `json:"-"`
+	ssg   ssg.Ssg      `json:"-"`
+	order int          // Declaration order in manifest file, see Manifest.Keys
+	log   *slog.Logger // Logger of the site, derived from the logger of ApplyManifestReports
 
 	CleanUp           bool                   `json:"-"` // Remove files in Copies before copying them
 	Copies            map[string]CopyTargets `json:"-"`
 	GenerateIndex     bool                   `json:"-"`
 	GenerateIndexMode IndexGeneratorMode     `json:"-"`
 	Replaces          Replaces               `json:"-"`
+	Symlinks          ssg.SymlinkPolicy      `json:"-"` // Symlink policy for both build and copies
+	Dotfiles          []string               `json:"-"` // Patterns of dotfiles to build, see ssg.AllowDotfiles
+	PathStrategy      ssg.PathStrategy       `json:"-"`
+	Redirects         map[string]string      `json:"-"` // Site-wide redirects, see ssg.WithRedirects
+	AllowOverwrites   bool                   `json:"-"` // Allow duplicate output targets, see ssg.AllowOverwrites
+	RewriteLinks      bool                   `json:"-"` // Rewrite links to .md sources, see ssg.RewriteLinks
+	Images            *Images                `json:"-"` // Image pipeline, disabled if nil
+	Fingerprint       *Fingerprint           `json:"-"` // Asset fingerprinting, disabled if nil
+	Integrity         *Integrity             `json:"-"` // Subresource Integrity, disabled if nil
+	Compression       ssg.Compression        `json:"-"` // Precompressed siblings of outputs, see ssg.WithCompression
+	DependsOn         []string               `json:"-"` // Keys of sites applied before this site, see Manifest.Schedule
+	Minify            Minify                 `json:"-"` // Minifiers, overridden by minify flags of FlagsV2
+	Writers           uint                   `json:"-"` // Concurrent output writers, see ssg.Writers
+	Markdown          *Markdown              `json:"-"` // Markdown options, defaults of ssg-go if nil, overridden by --markdown flags
 }
 
+// NewManifest reads and strictly validates manifest from filename.
+// Unlike unmarshaling a Manifest with json.Unmarshal, unknown keys
+// and sites without src or dst are errors. YAML and TOML manifests
+// are detected by extensions, see [ManifestFormat] and [ValidateManifest].
+//
+// ${env:NAME} in string values is replaced with environment variable NAME.
+// Use [NewManifestVars] to also replace ${var:NAME}.
 func NewManifest(filename string) (Manifest, error) {
+	return NewManifestVars(filename, nil)
+}
+
+// NewManifestVars is like [NewManifest], and also replaces ${var:NAME}
+// in string values with vars[NAME]. Undefined variables are errors,
+// unless given defaults like ${var:NAME:-default}. $${ escapes ${.
+func NewManifestVars(filename string, vars map[string]string) (Manifest, error) {
 	b, err := os.ReadFile(filename)
 	if err != nil {
 		slog.Error("failed to read manifest file")
 		return Manifest{}, fmt.Errorf("failed to read manifest from file '%s': %w", filename, err)
 	}
-	m := Manifest{}
-	err = json.Unmarshal(b, &m)
+	m, err := parseManifest(filename, b, vars, false)
 	if err != nil {
-		return Manifest{}, nil
+		return Manifest{}, err
 	}
 
 	return m, nil
@@ -69,27 +107,137 @@ type ReplaceTarget struct {
 
 type Replaces map[string]ReplaceTarget
 
+// Select returns sites in m with keys matching any of glob patterns sites,
+// or all sites if sites is empty, except those matching any of patterns exclude.
+// Patterns are matched with [path.Match], e.g. blog-* matches blog-staging.
+func (m Manifest) Select(sites, exclude []string) (Manifest, error) {
+	if len(sites) == 0 && len(exclude) == 0 {
+		return m, nil
+	}
+	match := func(patterns []string, key string) (bool, error) {
+		for _, pattern := range patterns {
+			ok, err := path.Match(pattern, key)
+			if err != nil {
+				return false, fmt.Errorf("bad site pattern '%s': %w", pattern, err)
+			}
+			if ok {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	selected := make(Manifest)
+	for key, site := range m {
+		included := len(sites) == 0
+		if !included {
+			ok, err := match(sites, key)
+			if err != nil {
+				return nil, err
+			}
+			included = ok
+		}
+		excluded, err := match(exclude, key)
+		if err != nil {
+			return nil, err
+		}
+		if included && !excluded {
+			selected[key] = site
+		}
+	}
+	return selected, nil
+}
+
 func (s *Site) Src() string { return s.ssg.Src }
 func (s *Site) Dst() string { return s.ssg.Dst }
 
-func (s *Site) UnmarshalJSON(b []byte) error {
-	var site struct {
-		Src   string `json:"src"`
-		Dst   string `json:"dst"`
-		Title string `json:"title"`
-		Url   string `json:"url"`
+// Check returns broken links in HTML files under the site's dst. See [ssg.Check].
+func (s *Site) Check() ([]ssg.BrokenLink, error) { return ssg.Check(s.ssg.Dst, s.ssg.Url) }
+
+// manifestSite is the JSON representation of a Site in manifests
+type manifestSite struct {
+	Src   string `json:"src"`
+	Dst   string `json:"dst"`
+	Title string `json:"title"`
+	Url   string `json:"url"`
+
+	Extends           string                 `json:"extends"` // Resolved when loading manifests, see loader
+	DependsOn         []string               `json:"depends-on"`
+	Copies            map[string]CopyTargets `json:"copies"`
+	CleanUp           bool                   `json:"cleanup"`
+	GenerateIndex     bool                   `json:"generate-index"`
+	GenerateIndexMode IndexGeneratorMode     `json:"generate-index-mode"`
+	Replaces          Replaces               `json:"replaces"`
+	Symlinks          string                 `json:"symlinks"`
+	Dotfiles          []string               `json:"dotfiles"`
+	PathStrategy      string                 `json:"path-strategy"`
+	Redirects         map[string]string      `json:"redirects"`
+	AllowOverwrites   bool                   `json:"allow-overwrites"`
+	RewriteLinks      bool                   `json:"rewrite-links"`
+	Images            *Images                `json:"images"`
+	Fingerprint       *Fingerprint           `json:"fingerprint"`
+	Integrity         *Integrity             `json:"integrity"`
+	Compress          *ssg.Compression       `json:"compress"`
+	Minify            Minify                 `json:"minify"`
+	Writers           uint                   `json:"writers"`
+	Markdown          *Markdown              `json:"markdown"`
+}
 
-		Copies            map[string]CopyTargets `json:"copies"`
-		CleanUp           bool                   `json:"cleanup"`
-		GenerateIndex     bool                   `json:"generate-index"`
-		GenerateIndexMode IndexGeneratorMode     `json:"generate-index-mode"`
-		Replaces          Replaces               `json:"replaces"`
-	}
+func (s *Site) UnmarshalJSON(b []byte) error {
+	var site manifestSite
 
 	err := json.Unmarshal(b, &site)
 	if err != nil {
 		return err
 	}
+	symlinks, err := ssg.ParseSymlinkPolicy(site.Symlinks)
+	if err != nil {
+		return err
+	}
+	paths, err := ssg.ParsePathStrategy(site.PathStrategy)
+	if err != nil {
+		return err
+	}
+	for _, pattern := range site.Dotfiles {
+		_, err := filepath.Match(pattern, "")
+		if err != nil {
+			return fmt.Errorf("bad dotfile pattern '%s': %w", pattern, err)
+		}
+	}
+	if site.Images != nil {
+		err := site.Images.validate()
+		if err != nil {
+			return err
+		}
+	}
+	if site.Fingerprint != nil {
+		err := site.Fingerprint.validate()
+		if err != nil {
+			return err
+		}
+	}
+	if site.Integrity != nil {
+		err := site.Integrity.validate()
+		if err != nil {
+			return err
+		}
+	}
+	var compression ssg.Compression
+	if site.Compress != nil {
+		compression = *site.Compress
+		if compression.MinSize < 0 {
+			return fmt.Errorf("bad compress min-size %d", compression.MinSize)
+		}
+	}
+	for from, to := range site.Redirects {
+		if from == "" || to == "" {
+			return fmt.Errorf("bad redirect from '%s' to '%s'", from, to)
+		}
+	}
+	prepared, err := ssg.Prepare(site.Src, site.Dst, site.Title, site.Url)
+	if err != nil {
+		return err
+	}
 
 	*s = Site{
 		Copies:            site.Copies,
@@ -97,12 +245,21 @@ func (s *Site) UnmarshalJSON(b []byte) error {
 		CleanUp:           site.CleanUp,
 		GenerateIndex:     site.GenerateIndex,
 		GenerateIndexMode: site.GenerateIndexMode,
-		ssg: ssg.New(
-			site.Src,
-			site.Dst,
-			site.Title,
-			site.Url,
-		),
+		Symlinks:          symlinks,
+		Dotfiles:          site.Dotfiles,
+		PathStrategy:      paths,
+		Redirects:         site.Redirects,
+		AllowOverwrites:   site.AllowOverwrites,
+		RewriteLinks:      site.RewriteLinks,
+		Images:            site.Images,
+		Fingerprint:       site.Fingerprint,
+		Integrity:         site.Integrity,
+		Compression:       compression,
+		DependsOn:         site.DependsOn,
+		Minify:            site.Minify,
+		Writers:           site.Writers,
+		Markdown:          site.Markdown,
+		ssg:               prepared,
 	}
 	return nil
 }
@@ -246,10 +403,15 @@ func (s *Stage) Ok(targets ...Stage) bool {
 }
 
 func (s manifestError) Error() string {
+	return fmt.Sprintf("[%s %s] %s", s.stage, s.key, s.detail())
+}
+
+// detail returns the error message without stage and key
+func (s manifestError) detail() string {
 	if s.err == nil {
-		return fmt.Sprintf("[%s %s] %s", s.stage, s.key, s.msg)
+		return s.msg
 	}
-	return fmt.Errorf("[%s %s] %s: %w", s.stage, s.key, s.msg, s.err).Error()
+	return fmt.Sprintf("%s: %s", s.msg, s.err.Error())
 }
 
 func (s manifestError) Unwrap() error {
@@ -279,37 +441,127 @@ func (s Stage) String() string {
 }
 
 func ApplyManifestV2(m Manifest, f FlagsV2, do Stage) error {
-	slog.SetDefault(newLogger())
-	slog.Info("stages",
+	_, err := ApplyManifestReports(m, f, do)
+	return err
+}
+
+// ApplyManifestReports is like ApplyManifestV2,
+// but also returns build reports of the sites built.
+func ApplyManifestReports(m Manifest, f FlagsV2, do Stage) (Reports, error) {
+	m, err := m.Select(f.Sites, f.ExcludeSites)
+	if err != nil {
+		return nil, err
+	}
+	_, err = f.markdown(nil)
+	if err != nil {
+		return nil, err
+	}
+	waves, err := m.Schedule()
+	if err != nil {
+		return nil, err
+	}
+
+	logs := io.Writer(os.Stdout)
+	if f.Report == "-" || f.DryRun {
+		// Keep stdout clean for the report or changes
+		logs = os.Stderr
+	}
+	// Sites log with loggers derived from logger,
+	// leaving slog.Default() of the caller untouched
+	logger := newLogger(logs)
+	logger.Info("stages",
 		StageCleanUp.String(), do.Ok(StageCleanUp),
 		StageCopy.String(), do.Ok(StageCopy),
 		StageBuild.String(), do.Ok(StageBuild),
 	)
+	if len(m) == 0 {
+		logger.Warn("no sites selected", "sites", f.Sites, "exclude_sites", f.ExcludeSites)
+	}
 
-	targets, err := collect(m)
+	targets, err := collect(m, logger)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	a := &applier{
+		m:       m,
+		f:       f,
+		logger:  logger,
+		reports: make(Reports),
+		failed:  make(map[string]bool),
 	}
 	if do.Ok(StageCleanUp) {
-		err = cleanup(m, targets)
+		err = a.sites(m.Keys(), func(key string) error {
+			if f.DryRun {
+				return cleanupDryRun(key, m[key], targets[key])
+			}
+			return cleanup(key, m[key], targets[key], logger)
+		})
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	// Copy
-	old := slog.Default()
-	for key, site := range m {
-		slog.SetDefault(old.
+	next := StageBuild
+	if do.Ok(StageCopy) {
+		next = StageCopy
+	}
+	for _, keys := range waves {
+		if do.Ok(next) {
+			a.skip(keys, next)
+		}
+		err = a.copy(keys, do)
+		if err != nil {
+			return a.reports, err
+		}
+		err = a.build(keys, do)
+		if err != nil {
+			return a.reports, err
+		}
+	}
+	return a.reports, a.err()
+}
+
+// applier applies stages to sites of m, recording failed sites
+// so that their later stages and sites depending on them are skipped
+type applier struct {
+	m      Manifest
+	f      FlagsV2
+	logger *slog.Logger
+
+	mut     sync.Mutex
+	reports Reports
+	failed  map[string]bool
+	errs    []error // Errors of failed sites, in order of stages and keys
+}
+
+// copy applies stage copy to sites keys
+func (a *applier) copy(keys []string, do Stage) error {
+	if !do.Ok(StageCopy) {
+		a.logger.WithGroup("copy").Info("skipping stage copy", "keys", keys)
+		return nil
+	}
+	return a.sites(keys, func(key string) error {
+		site := a.m[key]
+		site.log = a.logger.
 			WithGroup("copy").
 			With(
 				"key", key,
 				"url", site.ssg.Url,
-			),
-		)
-		if !do.Ok(StageCopy) {
-			slog.Info("skipping stage copy")
-			break
+			)
+
+		if a.f.DryRun {
+			changes, err := site.CopyDryRun(a.f.Diff)
+			if err != nil {
+				return manifestError{
+					err:   err,
+					key:   key,
+					msg:   "failed to dry-run copy",
+					stage: StageCopy,
+				}
+			}
+			printChanges(StageCopy, key, changes)
+			return nil
 		}
 
 		if err := site.Copy(); err != nil {
@@ -320,39 +572,40 @@ func ApplyManifestV2(m Manifest, f FlagsV2, do Stage) error {
 				stage: StageCopy,
 			}
 		}
+		return nil
+	})
+}
 
-		slog.SetDefault(old)
+// build applies stage build to sites keys, adding reports of the builds
+func (a *applier) build(keys []string, do Stage) error {
+	if !do.Ok(StageBuild) {
+		a.logger.WithGroup("build").Info("skipping stage build", "keys", keys)
+		return nil
 	}
-
-	// Build
-	for key, site := range m {
-		log := old.
+	return a.sites(keys, func(key string) error {
+		site := a.m[key]
+		site.log = a.logger.
 			WithGroup("build").
 			With(
 				"key", key,
 				"url", site.ssg.Url,
 			)
 
-		if !do.Ok(StageBuild) {
-			log.Info("skipping stage build")
-			break
-		}
+		b := newManifestBuilder(site, a.f)
+		site.log.Info("building site")
 
-		slog.SetDefault(log)
-		b := newManifestBuilder(site, f)
-
-		log.
-			With(
-				"key", key,
-				"url", site.ssg.Url,
-				// @TODO: Len logs below will be removed
-				// "len_hooks", len(b.Hooks()),
-				// "len_hooks_generate", len(b.HooksGenerate()),
-				// "len_pipelines", len(b.Pipelines()),
-			).
-			Info("building site")
-
-		if err := b.ssg.Generate(); err != nil {
+		if a.f.DryRun {
+			changes, err := b.ssg.DryRun(a.f.Diff)
+			if err != nil {
+				return manifestError{
+					err:   err,
+					key:   key,
+					msg:   "failed to dry-run build",
+					stage: StageBuild,
+				}
+			}
+			printChanges(StageBuild, key, changes)
+		} else if err := b.ssg.Generate(); err != nil {
 			return manifestError{
 				err:   err,
 				key:   key,
@@ -360,19 +613,110 @@ func ApplyManifestV2(m Manifest, f FlagsV2, do Stage) error {
 				stage: StageBuild,
 			}
 		}
+
+		a.mut.Lock()
+		a.reports[key] = b.ssg.Report()
+		a.mut.Unlock()
+		return nil
+	})
+}
+
+// sites calls apply for sites keys not yet failed, with up to f.Jobs sites at a time.
+// Errors of failed sites are recorded, and returned joined in order of keys.
+//
+// With f.ContinueOnError, all sites are applied and nil is returned,
+// leaving the errors to a.err. Otherwise no sites are started after
+// the first failure, although sites already started with f.Jobs > 1
+// are still applied.
+func (a *applier) sites(keys []string, apply func(key string) error) error {
+	var pending []string
+	for _, key := range keys {
+		if !a.failed[key] {
+			pending = append(pending, key)
+		}
+	}
+
+	errs := make([]error, len(pending))
+	if a.f.Jobs <= 1 {
+		for i, key := range pending {
+			errs[i] = apply(key)
+			if errs[i] != nil && !a.f.ContinueOnError {
+				break
+			}
+		}
+	} else {
+		sem := make(chan struct{}, a.f.Jobs)
+		wg := new(sync.WaitGroup)
+		failed := new(atomic.Bool)
+		for i, key := range pending {
+			sem <- struct{}{}
+			if failed.Load() && !a.f.ContinueOnError {
+				<-sem
+				break
+			}
+			wg.Add(1)
+			go func() {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				errs[i] = apply(key)
+				if errs[i] != nil {
+					failed.Store(true)
+				}
+			}()
+		}
+		wg.Wait()
 	}
-	return nil
+
+	for i, err := range errs {
+		if err != nil {
+			a.failed[pending[i]] = true
+			a.errs = append(a.errs, err)
+		}
+	}
+	if a.f.ContinueOnError {
+		return nil
+	}
+	return errors.Join(errs...)
+}
+
+// skip records sites keys depending on failed sites as failed in stage
+func (a *applier) skip(keys []string, stage Stage) {
+	for _, key := range keys {
+		if a.failed[key] {
+			continue
+		}
+		for _, dep := range a.m[key].DependsOn {
+			if !a.failed[dep] {
+				continue
+			}
+			a.failed[key] = true
+			a.errs = append(a.errs, manifestError{
+				key:   key,
+				msg:   fmt.Sprintf("skipped, depends on failed site '%s'", dep),
+				stage: stage,
+			})
+			break
+		}
+	}
+}
+
+// err returns errors of all failed sites joined
+func (a *applier) err() error {
+	return errors.Join(a.errs...)
 }
 
-func collect(m Manifest) (map[string]ssg.Set, error) {
+func collect(m Manifest, logger *slog.Logger) (map[string]ssg.Set, error) {
 	// Collect and detect duplicate write dups
 	dups := make(ssg.Set)
 	targets := make(map[string]ssg.Set)
-	for key, site := range m {
+	for _, key := range m.Keys() {
+		site := m[key]
 		if targets[key] == nil {
 			targets[key] = make(ssg.Set)
 		}
-		logger := slog.Default().WithGroup("collect").With("key", key, "url", site.ssg.Url)
+		logger := logger.WithGroup("collect").With("key", key, "url", site.ssg.Url)
 		for src, dests := range site.Copies {
 			for i := range dests {
 				dst := dests[i]
@@ -397,38 +741,126 @@ func collect(m Manifest) (map[string]ssg.Set, error) {
 	return targets, nil
 }
 
-func cleanup(m Manifest, targets map[string]ssg.Set) error {
-	for key, site := range m {
-		if !site.CleanUp {
+// cleanup removes copy targets of site key, logging with logger
+func cleanup(key string, site Site, targets ssg.Set, logger *slog.Logger) error {
+	if !site.CleanUp {
+		return nil
+	}
+	logger = logger.
+		WithGroup("cleanup").
+		With("key", key, "url", site.ssg.Url)
+
+	for target := range targets {
+		logger.Info("cleaning up", "target", target)
+		err := os.RemoveAll(target)
+		if err != nil && os.IsNotExist(err) {
+			continue
+		}
+		if err == nil {
 			continue
 		}
-		logger := slog.Default().
-			WithGroup("cleanup").
-			With("key", key, "url", site.ssg.Url)
+		return manifestError{
+			err:   err,
+			key:   key,
+			msg:   "failed to cleanup",
+			stage: StageCleanUp,
+		}
+	}
+	return nil
+}
 
-		siteTargets := targets[key]
-		for target := range siteTargets {
-			logger.Info("cleaning up", "target", target)
-			err := os.RemoveAll(target)
-			if err != nil && os.IsNotExist(err) {
-				continue
-			}
-			if err == nil {
-				continue
-			}
+// cleanupDryRun prints files that would be removed by cleanup
+func cleanupDryRun(key string, site Site, targets ssg.Set) error {
+	if !site.CleanUp {
+		return nil
+	}
+
+	var changes []ssg.Change
+	for target := range targets {
+		removed, err := ssg.Removals(target)
+		if err != nil {
 			return manifestError{
 				err:   err,
 				key:   key,
-				msg:   "failed to cleanup",
+				msg:   "failed to dry-run cleanup",
 				stage: StageCleanUp,
 			}
 		}
+		changes = append(changes, removed...)
 	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Target < changes[j].Target
+	})
+	printChanges(StageCleanUp, key, changes)
 	return nil
 }
 
+// printChanges prints changes of site key in stage at once,
+// so that changes of sites applied concurrently are not interleaved
+func printChanges(stage Stage, key string, changes []ssg.Change) {
+	b := bytes.NewBuffer(nil)
+	ssg.Fprintf(b, "[%s %s]\n", stage, key)
+	ssg.FprintChanges(b, changes)
+	os.Stdout.Write(b.Bytes())
+}
+
+// logger returns logger of s, or a new logger writing to stdout
+// if s is not applied with ApplyManifestReports
+func (s *Site) logger() *slog.Logger {
+	if s.log == nil {
+		s.log = newLogger(os.Stdout)
+	}
+	return s.log
+}
+
 func (s *Site) Copy() error {
-	logger := slog.Default()
+	logger := s.logger()
+	dirs, perms, err := s.scanCopies(true)
+	if err != nil {
+		return err
+	}
+
+	for cpSrc, cpDsts := range s.Copies {
+		for _, cpDst := range cpDsts {
+			logger := logger.With("phase", "copy", "cpSrc", cpSrc, "cpDst", cpDst)
+			err := copyFiles(dirs, cpSrc, cpDst, perms, s.Symlinks, logger)
+			if err != nil {
+				logger.Error("failed to copy file")
+				return fmt.Errorf("failed to copy directory '%s'->'%s': %w", cpSrc, cpDst.Target, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// CopyDryRun returns changes to copy targets that would be made by Copy,
+// without copying anything.
+func (s *Site) CopyDryRun(diff bool) ([]ssg.Change, error) {
+	dirs, perms, err := s.scanCopies(false)
+	if err != nil {
+		return nil, err
+	}
+
+	var outputs []ssg.OutputFile
+	for cpSrc, cpDsts := range s.Copies {
+		for _, cpDst := range cpDsts {
+			copies, err := copyOutputs(dirs, cpSrc, cpDst, perms, s.Symlinks, s.logger())
+			if err != nil {
+				return nil, fmt.Errorf("failed to read copy src '%s'->'%s': %w", cpSrc, cpDst.Target, err)
+			}
+			outputs = append(outputs, copies...)
+		}
+	}
+
+	return ssg.Compare(outputs, diff)
+}
+
+// scanCopies validates copy sources and targets, and returns existing directories
+// among them with their permissions. If prepare is true, parent directories
+// of missing copy targets are created.
+func (s *Site) scanCopies(prepare bool) (ssg.Set, map[string]fs.FileMode, error) {
+	logger := s.logger()
 	dirs := make(ssg.Set)
 	perms := make(map[string]fs.FileMode)
 
@@ -439,31 +871,31 @@ func (s *Site) Copy() error {
 				With("phase", "scan", "cpSrc", cpSrc, "cpDst", cpDst)
 
 			if len(cpSrc) == 0 {
-				return fmt.Errorf("found empty copy src")
+				return nil, nil, fmt.Errorf("found empty copy src")
 			}
 			if len(cpDst.Target) == 0 {
-				return fmt.Errorf("found empty copy dst")
+				return nil, nil, fmt.Errorf("found empty copy dst")
 			}
 
+			// Like cp -H, symlinked copy src is always followed,
+			// while symlinks under it are handled by the symlink policy
 			ssrc, err := os.Stat(cpSrc)
 			if err != nil {
 				logger.Error("failed to stat copy src")
-				return fmt.Errorf("failed to stat copy src '%s': %w", cpSrc, err)
-			}
-			if ssg.FileIs(ssrc, os.ModeSymlink) {
-				logger.Error("copy src is symlink")
-				return fmt.Errorf("copy src is symlink: '%s'", cpSrc)
+				return nil, nil, fmt.Errorf("failed to stat copy src '%s': %w", cpSrc, err)
 			}
 
 			sdst, err := os.Stat(cpDst.Target)
 			if err != nil {
 				if !os.IsNotExist(err) {
 					logger.Error("failed to stat copy dst", "error", err)
-					return fmt.Errorf("failed to stat copy dst '%s': %w", cpDst, err)
+					return nil, nil, fmt.Errorf("failed to stat copy dst '%s': %w", cpDst, err)
 				}
-				err = os.MkdirAll(filepath.Dir(cpDst.Target), os.ModePerm)
-				if err != nil {
-					return fmt.Errorf("fail to prepare copy dst '%s': %w", cpDst, err)
+				if prepare {
+					err = os.MkdirAll(filepath.Dir(cpDst.Target), os.ModePerm)
+					if err != nil {
+						return nil, nil, fmt.Errorf("fail to prepare copy dst '%s': %w", cpDst, err)
+					}
 				}
 			}
 
@@ -478,18 +910,7 @@ func (s *Site) Copy() error {
 		}
 	}
 
-	for cpSrc, cpDsts := range s.Copies {
-		for _, cpDst := range cpDsts {
-			logger := logger.With("phase", "copy", "cpSrc", cpSrc, "cpDst", cpDst)
-			err := copyFiles(dirs, cpSrc, cpDst, perms)
-			if err != nil {
-				logger.Error("failed to copy file")
-				return fmt.Errorf("failed to copy directory '%s'->'%s': %w", cpSrc, cpDst.Target, err)
-			}
-		}
-	}
-
-	return nil
+	return dirs, perms, nil
 }
 
 func cp(src string, dst CopyTarget, perm fs.FileMode) error {
@@ -525,31 +946,48 @@ func cp(src string, dst CopyTarget, perm fs.FileMode) error {
 	return nil
 }
 
-func cpRecurse(src string, dst CopyTarget) error {
-	dstRoot := dst.Target
-	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+// cpLink recreates symlink at path under src at dst
+func cpLink(src, path string, dst CopyTarget) error {
+	link, err := ssg.ReadLink(src, path)
+	if err != nil {
+		return fmt.Errorf("error reading symlink: %w", err)
+	}
+
+	dir := filepath.Dir(dst.Target)
+	err = os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("error preparing dst directory at '%s': %w", dir, err)
+	}
+
+	stat, err := os.Lstat(dst.Target)
+	if err == nil && !stat.IsDir() {
+		err = os.Remove(dst.Target)
 		if err != nil {
-			return err
+			return fmt.Errorf("error removing existing dst: %w", err)
 		}
-		if d == nil {
-			return nil
+	}
+
+	err = os.Symlink(link, dst.Target)
+	if err != nil {
+		return fmt.Errorf("error writing symlink to dst: %w", err)
+	}
+	return nil
+}
+
+func cpRecurse(src string, dst CopyTarget, symlinks ssg.SymlinkPolicy, logger *slog.Logger) error {
+	dstRoot := dst.Target
+	err := walkCopySrc(src, symlinks, logger, func(path, rel string, d fs.DirEntry) error {
+		out := CopyTarget{
+			Target: filepath.Join(dstRoot, rel),
+			Force:  dst.Force,
 		}
-		if d.IsDir() {
-			return nil
+		if d.Type()&fs.ModeSymlink != 0 {
+			return cpLink(src, path, out)
 		}
 		info, err := d.Info()
 		if err != nil {
 			return err
 		}
-		rel, err := filepath.Rel(src, path)
-		if err != nil {
-			return err
-		}
-
-		out := CopyTarget{
-			Target: filepath.Join(dstRoot, rel),
-			Force:  dst.Force,
-		}
 		return cp(path, out, info.Mode().Perm())
 	})
 
@@ -560,11 +998,93 @@ func cpRecurse(src string, dst CopyTarget) error {
 	return nil
 }
 
+// walkCopySrc walks copy src directory, calling fn for each file
+// with its path relative to src. Symlinks under src are handled
+// according to symlinks. With [ssg.SymlinkCopy], fn is called
+// with DirEntry of the symlinks themselves.
+func walkCopySrc(
+	src string,
+	symlinks ssg.SymlinkPolicy,
+	logger *slog.Logger,
+	fn func(path, rel string, d fs.DirEntry) error,
+) error {
+	real, err := ssg.RealPath(src)
+	if err != nil {
+		return err
+	}
+	return walkCopyDir(src, src, []string{real}, symlinks, logger, fn)
+}
+
+// walkCopyDir walks dir under copy src, with following being
+// resolved directories being walked, used to detect symlink cycles
+func walkCopyDir(
+	src string,
+	dir string,
+	following []string,
+	symlinks ssg.SymlinkPolicy,
+	logger *slog.Logger,
+	fn func(path, rel string, d fs.DirEntry) error,
+) error {
+	// WalkDir does not follow root if it's a symlink,
+	// unless it has a trailing separator
+	root := dir + string(filepath.Separator)
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root || d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if d.Type()&fs.ModeSymlink == 0 {
+			return fn(path, rel, d)
+		}
+
+		switch symlinks {
+		case ssg.SymlinkCopy:
+			return fn(path, rel, d)
+
+		case ssg.SymlinkFollow:
+			stat, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+			if !stat.IsDir() {
+				return fn(path, rel, fs.FileInfoToDirEntry(stat))
+			}
+
+			real, err := ssg.RealPath(path)
+			if err != nil {
+				return err
+			}
+			parent, err := ssg.RealPath(filepath.Dir(path))
+			if err != nil {
+				return err
+			}
+			for _, walking := range append([]string{parent}, following...) {
+				if ssg.IsAncestor(real, walking) {
+					logger.Warn("skipping symlink cycle", "path", path, "target", real)
+					return nil
+				}
+			}
+			return walkCopyDir(src, path, append(following, real), symlinks, logger, fn)
+		}
+
+		logger.Info("skipping symlink", "path", path)
+		return nil
+	})
+}
+
 func copyFiles(
 	existingDirs ssg.Set,
 	src string,
 	dst CopyTarget,
 	permsCache map[string]fs.FileMode,
+	symlinks ssg.SymlinkPolicy,
+	logger *slog.Logger,
 ) error {
 	isDirSrc, isDirDst := existingDirs.Contains(src), existingDirs.Contains(dst.Target)
 	isDirBoth := isDirSrc && isDirDst
@@ -581,7 +1101,7 @@ func copyFiles(
 
 	// Copy dir to dir, with target dir existing
 	case isDirBoth:
-		return cpRecurse(src, dst)
+		return cpRecurse(src, dst, symlinks, logger)
 
 	// Copy file to dir, i.e. cp foo.json ./some-dir/
 	// which will just writes out to ./some-dir/foo.json
@@ -592,3 +1112,51 @@ func copyFiles(
 
 	return cp(src, dst, permsCache[src])
 }
+
+// copyOutputs reads files to be copied by copyFiles into outputs
+func copyOutputs(
+	existingDirs ssg.Set,
+	src string,
+	dst CopyTarget,
+	permsCache map[string]fs.FileMode,
+	symlinks ssg.SymlinkPolicy,
+	logger *slog.Logger,
+) (
+	[]ssg.OutputFile,
+	error,
+) {
+	if existingDirs.Contains(src) {
+		var outputs []ssg.OutputFile
+		err := walkCopySrc(src, symlinks, logger, func(path, rel string, d fs.DirEntry) error {
+			if d.Type()&fs.ModeSymlink != 0 {
+				link, err := ssg.ReadLink(src, path)
+				if err != nil {
+					return err
+				}
+				outputs = append(outputs, ssg.OutputSymlink(filepath.Join(dst.Target, rel), path, link))
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			outputs = append(outputs, ssg.Output(filepath.Join(dst.Target, rel), path, data, info.Mode().Perm()))
+			return nil
+		})
+		return outputs, err
+	}
+
+	target := dst.Target
+	if existingDirs.Contains(dst.Target) {
+		target = filepath.Join(dst.Target, filepath.Base(src))
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return nil, err
+	}
+	return []ssg.OutputFile{ssg.Output(target, src, data, permsCache[src])}, nil
+}
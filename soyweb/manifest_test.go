@@ -4,10 +4,15 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/fs"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	. "github.com/soyart/ssg/soyweb"
+	"github.com/soyart/ssg/ssg-go"
 )
 
 func TestManifestUnmarshal(t *testing.T) {
@@ -570,6 +575,802 @@ func TestManifest(t *testing.T) {
 	}
 }
 
+func TestManifestReports(t *testing.T) {
+	manifestJSON := `{
+		"myblog": {
+			"url": "https://my.blog",
+			"src": "../testdata/myblog/src",
+			"dst": "../testdata/myblog/dst-test-reports",
+			"generate-index": true
+		}
+	}`
+
+	var m Manifest
+	err := json.Unmarshal([]byte(manifestJSON), &m)
+	if err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	site := m["myblog"]
+	defer os.RemoveAll(site.Dst())
+
+	logger := slog.Default()
+	reports, err := ApplyManifestReports(m, FlagsV2{}, StageBuild)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if slog.Default() != logger {
+		t.Fatal("unexpected change of default logger")
+	}
+	report, ok := reports["myblog"]
+	if !ok || report == nil {
+		t.Fatalf("missing report for key myblog: %+v", reports)
+	}
+	if report.Src != site.Src() || report.Dst != site.Dst() {
+		t.Fatalf("unexpected src/dst in report: %s, %s", report.Src, report.Dst)
+	}
+	if len(report.Timings.Pipelines) != 1 {
+		t.Fatalf("unexpected len of pipeline timings %d", len(report.Timings.Pipelines))
+	}
+
+	foundIndex := false
+	for _, o := range report.Outputs {
+		if o.Target == prefix(site.Dst(), "index.html") {
+			foundIndex = o.Originator == prefix(site.Src(), "index.md")
+			break
+		}
+	}
+	if !foundIndex {
+		t.Fatalf("missing generated index in report outputs: %+v", report.Outputs)
+	}
+}
+
+func TestManifestDotfiles(t *testing.T) {
+	root := t.TempDir()
+	src, dst := filepath.Join(root, "src"), filepath.Join(root, "dst")
+	for _, path := range []string{
+		"index.md",
+		".well-known/security.txt",
+		".git/config",
+	} {
+		path = filepath.Join(src, path)
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			panic(err)
+		}
+		err = os.WriteFile(path, []byte("# Foo"), 0644)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	manifestJSON := fmt.Sprintf(`{
+		"dotfiles": {
+			"url": "https://dot.files",
+			"src": "%s",
+			"dst": "%s",
+			"dotfiles": [".well-known"]
+		}
+	}`, src, dst)
+
+	var m Manifest
+	err := json.Unmarshal([]byte(manifestJSON), &m)
+	if err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	_, err = ApplyManifestReports(m, FlagsV2{}, StageBuild)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = os.Stat(filepath.Join(dst, ".well-known", "security.txt"))
+	if err != nil {
+		t.Fatalf("expecting allowed dotfile to be built: %v", err)
+	}
+	_, err = os.Stat(filepath.Join(dst, ".git"))
+	if !os.IsNotExist(err) {
+		t.Fatalf("unexpected dotfile built: %v", err)
+	}
+
+	var site Site
+	err = json.Unmarshal([]byte(`{"src": "src", "dst": "dst", "dotfiles": ["[bad"]}`), &site)
+	if err == nil {
+		t.Fatal("expecting error from bad dotfile pattern")
+	}
+}
+
+func TestManifestRedirects(t *testing.T) {
+	root := t.TempDir()
+	src, dst := filepath.Join(root, "src"), filepath.Join(root, "dst")
+	files := map[string]string{
+		"index.md":      "# Index",
+		"blog/hello.md": ":ssg-alias /2023/hello.html\n# Hello",
+	}
+	for path, content := range files {
+		path = filepath.Join(src, path)
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			panic(err)
+		}
+		err = os.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	manifestJSON := fmt.Sprintf(`{
+		"redirects": {
+			"url": "https://redirects.example",
+			"src": "%s",
+			"dst": "%s",
+			"redirects": {
+				"/old-blog/": "/blog/"
+			}
+		}
+	}`, src, dst)
+
+	var m Manifest
+	err := json.Unmarshal([]byte(manifestJSON), &m)
+	if err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	_, err = ApplyManifestReports(m, FlagsV2{}, StageBuild)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for stub, url := range map[string]string{
+		"2023/hello.html":     "https://redirects.example/blog/hello.html",
+		"old-blog/index.html": "https://redirects.example/blog/",
+	} {
+		b, err := os.ReadFile(filepath.Join(dst, stub))
+		if err != nil {
+			t.Fatalf("missing redirect stub %s: %v", stub, err)
+		}
+		if !strings.Contains(string(b), fmt.Sprintf(`<link rel="canonical" href="%s">`, url)) {
+			t.Fatalf("unexpected redirect stub %s:\n%s", stub, b)
+		}
+	}
+	b, err := os.ReadFile(filepath.Join(dst, "_redirects"))
+	if err != nil {
+		t.Fatalf("missing _redirects: %v", err)
+	}
+	expected := "/2023/hello.html /blog/hello.html 301\n/old-blog/ /blog/ 301\n"
+	if string(b) != expected {
+		t.Fatalf("unexpected _redirects:\n%s", b)
+	}
+
+	var site Site
+	err = json.Unmarshal([]byte(`{"src": "src", "dst": "dst", "redirects": {"/old/": ""}}`), &site)
+	if err == nil {
+		t.Fatal("expecting error from empty redirect target")
+	}
+}
+
+func TestManifestCompress(t *testing.T) {
+	root := t.TempDir()
+	src, dst := filepath.Join(root, "src"), filepath.Join(root, "dst")
+	files := map[string]string{
+		"index.md": "# Index\n\n" + strings.Repeat("Some compressible text. ", 100),
+		"small.md": "# Small",
+	}
+	for path, content := range files {
+		path = filepath.Join(src, path)
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			panic(err)
+		}
+		err = os.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	manifestJSON := fmt.Sprintf(`{
+		"compress": {
+			"url": "https://compress.example",
+			"src": "%s",
+			"dst": "%s",
+			"compress": {
+				"gzip": true
+			}
+		}
+	}`, src, dst)
+
+	var m Manifest
+	err := json.Unmarshal([]byte(manifestJSON), &m)
+	if err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	_, err = ApplyManifestReports(m, FlagsV2{}, StageBuild)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = os.Stat(filepath.Join(dst, "index.html.gz"))
+	if err != nil {
+		t.Fatalf("missing index.html.gz: %v", err)
+	}
+	for _, path := range []string{"index.html.br", "small.html.gz"} {
+		_, err = os.Stat(filepath.Join(dst, path))
+		if !os.IsNotExist(err) {
+			t.Fatalf("unexpected %s: %v", path, err)
+		}
+	}
+
+	err = json.Unmarshal([]byte(`{"bad": {"compress": {"gzip": true, "min-size": -1}}}`), &m)
+	if err == nil {
+		t.Fatal("expecting error from negative min-size")
+	}
+}
+
+func TestManifestBuildOptions(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"index.md":  "# Index\n\nfirst\nsecond -- \"quoted\"\n",
+		"style.css": "body {\n  color: red;\n}\n",
+	}
+	for _, site := range []string{"options", "plain"} {
+		for path, content := range files {
+			path = filepath.Join(root, site, "src", path)
+			err := os.MkdirAll(filepath.Dir(path), 0755)
+			if err != nil {
+				panic(err)
+			}
+			err = os.WriteFile(path, []byte(content), 0644)
+			if err != nil {
+				panic(err)
+			}
+		}
+	}
+
+	manifestJSON := fmt.Sprintf(`{
+		"options": {
+			"url": "https://options.example",
+			"src": "%[1]s/options/src",
+			"dst": "%[1]s/options/dst",
+			"writers": 4,
+			"minify": {
+				"css": true
+			},
+			"markdown": {
+				"hard-line-break": true,
+				"smartypants": false
+			}
+		},
+		"plain": {
+			"url": "https://plain.example",
+			"src": "%[1]s/plain/src",
+			"dst": "%[1]s/plain/dst"
+		}
+	}`, root)
+
+	var m Manifest
+	err := json.Unmarshal([]byte(manifestJSON), &m)
+	if err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+
+	type testCase struct {
+		flags    FlagsV2
+		expected map[string][]string // Outputs and their expected content
+	}
+
+	tests := []testCase{
+		{
+			expected: map[string][]string{
+				"options/dst/style.css":  {"body{color:red}"},
+				"options/dst/index.html": {"first<br", "&quot;quoted&quot;"},
+				"plain/dst/style.css":    {"body {\n  color: red;\n}"},
+				"plain/dst/index.html":   {"first\nsecond", "&ldquo;quoted&rdquo;"},
+			},
+		},
+		{
+			flags: FlagsV2{MinifyCss: true, Writers: 2},
+			expected: map[string][]string{
+				"options/dst/style.css": {"body{color:red}"},
+				"plain/dst/style.css":   {"body{color:red}"},
+			},
+		},
+		{
+			// Flags disable options enabled by the manifest
+			flags: FlagsV2{NoMinifyCss: true, Markdown: []string{"hard-line-break=false", "smartypants"}},
+			expected: map[string][]string{
+				"options/dst/style.css":  {"body {\n  color: red;\n}"},
+				"options/dst/index.html": {"first\nsecond", "&ldquo;quoted&rdquo;"},
+				"plain/dst/style.css":    {"body {\n  color: red;\n}"},
+			},
+		},
+		{
+			flags: FlagsV2{MinifyCss: true, NoMinifyCss: true},
+			expected: map[string][]string{
+				"options/dst/style.css": {"body {\n  color: red;\n}"},
+				"plain/dst/style.css":   {"body {\n  color: red;\n}"},
+			},
+		},
+	}
+
+	for i := range tests {
+		tc := &tests[i]
+		_, err = ApplyManifestReports(m, tc.flags, StageBuild)
+		if err != nil {
+			t.Fatalf("[%d] unexpected error: %v", i, err)
+		}
+		for path, expected := range tc.expected {
+			b, err := os.ReadFile(filepath.Join(root, path))
+			if err != nil {
+				t.Fatalf("[%d] missing output %s: %v", i, path, err)
+			}
+			for _, s := range expected {
+				if !strings.Contains(string(b), s) {
+					t.Fatalf("[%d] missing '%s' in %s:\n%s", i, s, path, b)
+				}
+			}
+		}
+	}
+
+	for _, flag := range []string{"hard-line-breaks", "smartypants=no"} {
+		_, err = ApplyManifestReports(m, FlagsV2{Markdown: []string{flag}}, StageBuild)
+		if err == nil {
+			t.Fatalf("expecting error from bad markdown flag '%s'", flag)
+		}
+	}
+}
+
+func TestManifestSelect(t *testing.T) {
+	root := t.TempDir()
+	keys := []string{"blog", "blog-staging", "docs", "docs-staging"}
+	sites := make(map[string]any)
+	for _, key := range keys {
+		src := filepath.Join(root, key, "src")
+		err := os.MkdirAll(src, 0755)
+		if err != nil {
+			panic(err)
+		}
+		err = os.WriteFile(filepath.Join(src, "index.md"), []byte("# "+key), 0644)
+		if err != nil {
+			panic(err)
+		}
+		sites[key] = map[string]string{"src": src, "dst": filepath.Join(root, key, "dst")}
+	}
+	b, err := json.Marshal(sites)
+	if err != nil {
+		panic(err)
+	}
+	var m Manifest
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+
+	type testCase struct {
+		sites    []string
+		exclude  []string
+		expected []string
+	}
+	tests := []testCase{
+		{expected: keys},
+		{sites: []string{"docs"}, expected: []string{"docs"}},
+		{sites: []string{"blog*", "docs"}, expected: []string{"blog", "blog-staging", "docs"}},
+		{exclude: []string{"*-staging"}, expected: []string{"blog", "docs"}},
+		{sites: []string{"blog*"}, exclude: []string{"*-staging"}, expected: []string{"blog"}},
+		{sites: []string{"nope"}, expected: []string{}},
+	}
+	for i := range tests {
+		tc := &tests[i]
+		selected, err := m.Select(tc.sites, tc.exclude)
+		if err != nil {
+			t.Fatalf("[%d] unexpected error: %v", i, err)
+		}
+		if len(selected) != len(tc.expected) {
+			t.Fatalf("[%d] unexpected sites: expecting %v, got %d sites", i, tc.expected, len(selected))
+		}
+		for _, key := range tc.expected {
+			if _, ok := selected[key]; !ok {
+				t.Fatalf("[%d] missing site %s", i, key)
+			}
+		}
+	}
+	_, err = m.Select([]string{"[blog"}, nil)
+	if err == nil {
+		t.Fatal("expecting error from bad pattern")
+	}
+
+	flags := FlagsV2{FlagsSites: FlagsSites{Sites: []string{"*-staging"}, ExcludeSites: []string{"docs*"}}}
+	reports, err := ApplyManifestReports(m, flags, StageBuild)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(reports) != 1 || reports["blog-staging"] == nil {
+		t.Fatalf("unexpected reports: %+v", reports)
+	}
+	for _, key := range keys {
+		_, err := os.Stat(filepath.Join(root, key, "dst", "index.html"))
+		if built := err == nil; built != (key == "blog-staging") {
+			t.Fatalf("unexpected build of %s: %v", key, err)
+		}
+	}
+}
+
+func TestManifestJobs(t *testing.T) {
+	root := t.TempDir()
+	keys := []string{"a", "broken-1", "broken-2", "z"}
+	for _, jobs := range []int{1, 2} {
+		sites := make(map[string]any)
+		for _, key := range keys {
+			src := filepath.Join(root, key, "src")
+			if !strings.HasPrefix(key, "broken") {
+				err := os.MkdirAll(src, 0755)
+				if err != nil {
+					panic(err)
+				}
+				err = os.WriteFile(filepath.Join(src, "index.md"), []byte("# "+key), 0644)
+				if err != nil {
+					panic(err)
+				}
+			}
+			sites[key] = map[string]string{"src": src, "dst": filepath.Join(root, key, fmt.Sprintf("dst-%d", jobs))}
+		}
+		b, err := json.Marshal(sites)
+		if err != nil {
+			panic(err)
+		}
+		var m Manifest
+		err = json.Unmarshal(b, &m)
+		if err != nil {
+			t.Fatalf("failed to parse JSON: %v", err)
+		}
+
+		reports, err := ApplyManifestReports(m, FlagsV2{Jobs: jobs}, StageBuild)
+		if err == nil {
+			t.Fatalf("[jobs %d] unexpected nil error", jobs)
+		}
+		// No sites are started after the first failure, but with jobs > 1,
+		// broken-2 may have started before broken-1 failed
+		built := []string{"a"}
+		lines := strings.Split(err.Error(), "\n")
+		if len(lines) > jobs {
+			t.Fatalf("[jobs %d] unexpected errors: %v", jobs, err)
+		}
+		for i, line := range lines {
+			if !strings.HasPrefix(line, fmt.Sprintf("[build broken-%d] failed to build", i+1)) {
+				t.Fatalf("[jobs %d] unexpected error: %s", jobs, line)
+			}
+		}
+		if len(reports) != len(built) {
+			t.Fatalf("[jobs %d] unexpected reports: %+v", jobs, reports)
+		}
+		for _, key := range built {
+			if reports[key] == nil {
+				t.Fatalf("[jobs %d] missing report of %s", jobs, key)
+			}
+			_, err := os.Stat(filepath.Join(root, key, fmt.Sprintf("dst-%d", jobs), "index.html"))
+			if err != nil {
+				t.Fatalf("[jobs %d] missing outputs of %s: %v", jobs, key, err)
+			}
+		}
+	}
+}
+
+func TestManifestContinueOnError(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "src")
+	err := os.MkdirAll(src, 0755)
+	if err != nil {
+		panic(err)
+	}
+	err = os.WriteFile(filepath.Join(src, "index.md"), []byte("# Index"), 0644)
+	if err != nil {
+		panic(err)
+	}
+
+	manifestJSON := fmt.Sprintf(`{
+		"a":         {"src": "%[1]s/src", "dst": "%[1]s/a"},
+		"bad-build": {"src": "%[1]s/nope", "dst": "%[1]s/bad-build"},
+		"bad-copy":  {"src": "%[1]s/src", "dst": "%[1]s/bad-copy", "copies": {"%[1]s/nope": "%[1]s/nope-copy"}},
+		"dependent": {"src": "%[1]s/src", "dst": "%[1]s/dependent", "depends-on": ["bad-build"]},
+		"z":         {"src": "%[1]s/src", "dst": "%[1]s/z"}
+	}`, root)
+
+	var m Manifest
+	err = json.Unmarshal([]byte(manifestJSON), &m)
+	if err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+
+	for _, jobs := range []int{1, 3} {
+		flags := FlagsV2{Jobs: jobs, ContinueOnError: true}
+		reports, err := ApplyManifestReports(m, flags, StageCopy|StageBuild)
+		if err == nil {
+			t.Fatalf("[jobs %d] unexpected nil error", jobs)
+		}
+		if len(reports) != 2 || reports["a"] == nil || reports["z"] == nil {
+			t.Fatalf("[jobs %d] unexpected reports: %+v", jobs, reports)
+		}
+
+		summary := bytes.NewBuffer(nil)
+		FprintSummary(summary, err)
+		lines := strings.Split(strings.TrimSpace(summary.String()), "\n")
+		expected := []string{
+			"STAGE  SITE       ERROR",
+			"copy   bad-copy   failed to copy: ",
+			"build  bad-build  failed to build: ",
+			"copy   dependent  skipped, depends on failed site 'bad-build'",
+		}
+		if len(lines) != len(expected) {
+			t.Fatalf("[jobs %d] unexpected summary:\n%s", jobs, summary)
+		}
+		for i := range expected {
+			if !strings.HasPrefix(lines[i], expected[i]) {
+				t.Fatalf("[jobs %d] unexpected summary line %d: expecting '%s', got '%s'", jobs, i, expected[i], lines[i])
+			}
+		}
+	}
+
+	// Errors from loading manifests are listed in stage load
+	bad := filepath.Join(root, "bad.json")
+	err = os.WriteFile(bad, []byte(`{"a": {"src": "src", "dst": "src"}, "b.com": {"src": "src"}}`), 0644)
+	if err != nil {
+		panic(err)
+	}
+	_, err = NewManifest(bad)
+	if err == nil {
+		t.Fatal("unexpected nil error from bad manifest")
+	}
+	summary := bytes.NewBuffer(nil)
+	FprintSummary(summary, err)
+	lines := strings.Split(strings.TrimSpace(summary.String()), "\n")
+	expected := []string{
+		"STAGE  SITE   ERROR",
+		"load   a      " + bad + ":1:22: $.a.dst: src is identical to dst 'src'",
+		"load   b.com  " + bad + ":1:37: $[\"b.com\"]: missing required key 'dst'",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("unexpected summary:\n%s", summary)
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Fatalf("unexpected summary line %d: expecting '%s', got '%s'", i, expected[i], lines[i])
+		}
+	}
+
+	// Without ContinueOnError, the first failure stops
+	reports, err := ApplyManifestReports(m, FlagsV2{}, StageCopy|StageBuild)
+	if err == nil || !strings.HasPrefix(err.Error(), "[copy bad-copy] failed to copy") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(reports) != 0 {
+		t.Fatalf("unexpected reports: %+v", reports)
+	}
+}
+
+func TestSiteCheck(t *testing.T) {
+	root := t.TempDir()
+	src, dst := filepath.Join(root, "src"), filepath.Join(root, "dst")
+	err := os.MkdirAll(src, 0755)
+	if err != nil {
+		panic(err)
+	}
+	err = os.WriteFile(filepath.Join(src, "index.md"), []byte("# Index\n\n[Foo](foo.md) [Index](#index)\n"), 0644)
+	if err != nil {
+		panic(err)
+	}
+
+	manifestJSON := fmt.Sprintf(`{
+		"check": {
+			"url": "https://check.example",
+			"src": "%s",
+			"dst": "%s"
+		}
+	}`, src, dst)
+
+	var m Manifest
+	err = json.Unmarshal([]byte(manifestJSON), &m)
+	if err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	_, err = ApplyManifestReports(m, FlagsV2{}, StageBuild)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	site := m["check"]
+	broken, err := site.Check()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(broken) != 1 || broken[0].Link != "foo.md" || broken[0].Reason != ssg.LinkMarkdown {
+		t.Fatalf("unexpected broken links: %+v", broken)
+	}
+}
+
+func TestSiteCopyDryRun(t *testing.T) {
+	manifestJSON := `{
+		"johndoe.com": {
+			"src": "../testdata/johndoe.com/src",
+			"dst": "../testdata/johndoe.com/dst",
+			"copies": {
+				"../testdata/assets/some.txt": "../testdata/johndoe.com/src/dry-run/some-txt.txt",
+				"../testdata/assets/some/fonts": "../testdata/johndoe.com/src/dry-run/fonts"
+			}
+		}
+	}`
+
+	var m Manifest
+	err := json.Unmarshal([]byte(manifestJSON), &m)
+	if err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	site := m["johndoe.com"]
+	dir := "../testdata/johndoe.com/src/dry-run"
+	defer os.RemoveAll(dir)
+
+	assertChanges := func(t *testing.T, kind ssg.ChangeKind) {
+		changes, err := site.CopyDryRun(false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expecteds := []string{
+			prefix(dir, "fonts/fake-font-bold.ttf"),
+			prefix(dir, "fonts/fake-font.ttf"),
+			prefix(dir, "some-txt.txt"),
+		}
+		if len(changes) != len(expecteds) {
+			t.Fatalf("unexpected len of changes %d, expecting %d: %+v", len(changes), len(expecteds), changes)
+		}
+		for i := range changes {
+			if changes[i].Target != expecteds[i] {
+				t.Fatalf("unexpected target '%s', expecting '%s'", changes[i].Target, expecteds[i])
+			}
+			if changes[i].Kind != kind {
+				t.Fatalf("unexpected change kind '%s' for '%s', expecting '%s'", changes[i].Kind, changes[i].Target, kind)
+			}
+		}
+	}
+
+	assertChanges(t, ssg.ChangeAdded)
+	_, err = os.Stat(dir)
+	if !os.IsNotExist(err) {
+		t.Fatalf("unexpected copy target written by dry-run: %v", err)
+	}
+
+	err = site.Copy()
+	if err != nil {
+		t.Fatalf("unexpected error from copy: %v", err)
+	}
+	assertChanges(t, ssg.ChangeUnchanged)
+}
+
+func TestSiteCopySymlinks(t *testing.T) {
+	root := t.TempDir()
+	shared := filepath.Join(root, "shared")
+	err := os.MkdirAll(filepath.Join(shared, "fonts"), 0755)
+	if err != nil {
+		panic(err)
+	}
+	err = os.WriteFile(filepath.Join(shared, "fonts", "font.ttf"), []byte("font"), 0644)
+	if err != nil {
+		panic(err)
+	}
+	err = os.WriteFile(filepath.Join(shared, "style.css"), []byte("body {}"), 0644)
+	if err != nil {
+		panic(err)
+	}
+
+	// Copy src is a symlink to a directory with symlinks
+	assets := filepath.Join(root, "assets")
+	for link, target := range map[string]string{
+		assets:                              shared,
+		filepath.Join(shared, "css"):        "style.css",
+		filepath.Join(shared, "fonts-link"): "fonts",
+		filepath.Join(shared, "loop"):       ".",
+	} {
+		err = os.Symlink(target, link)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	tests := map[string]map[string]string{
+		"skip": {
+			"style.css":      "body {}",
+			"fonts/font.ttf": "font",
+		},
+		"follow": {
+			"style.css":           "body {}",
+			"fonts/font.ttf":      "font",
+			"css":                 "body {}",
+			"fonts-link/font.ttf": "font",
+		},
+		"copy": {
+			"style.css":           "body {}",
+			"fonts/font.ttf":      "font",
+			"css":                 "body {}",
+			"fonts-link":          "",
+			"fonts-link/font.ttf": "font",
+			"loop":                "",
+		},
+	}
+
+	for policy, expecteds := range tests {
+		t.Run(policy, func(t *testing.T) {
+			dst := filepath.Join(root, "dst-"+policy)
+			manifestJSON := fmt.Sprintf(`{
+				"src": "src",
+				"dst": "dst",
+				"symlinks": "%s",
+				"copies": {
+					"%s": "%s"
+				}
+			}`, policy, assets, dst)
+
+			var site Site
+			err := json.Unmarshal([]byte(manifestJSON), &site)
+			if err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			if string(site.Symlinks) != policy {
+				t.Fatalf("unexpected symlink policy '%s', expecting '%s'", site.Symlinks, policy)
+			}
+
+			err = site.Copy()
+			if err != nil {
+				t.Fatalf("unexpected error from copy: %v", err)
+			}
+
+			count := 0
+			err = filepath.WalkDir(dst, func(path string, d fs.DirEntry, err error) error {
+				if err != nil || d.IsDir() {
+					return err
+				}
+				count++
+				return nil
+			})
+			if err != nil {
+				panic(err)
+			}
+			// fonts-link/font.ttf is not walked when fonts-link is copied as symlink
+			if policy == "copy" {
+				count++
+			}
+			if count != len(expecteds) {
+				t.Fatalf("unexpected number of files copied %d, expecting %d", count, len(expecteds))
+			}
+			for path, content := range expecteds {
+				if content == "" {
+					_, err := os.Readlink(filepath.Join(dst, path))
+					if err != nil {
+						t.Fatalf("expecting '%s' to be a symlink: %v", path, err)
+					}
+					continue
+				}
+				b, err := os.ReadFile(filepath.Join(dst, path))
+				if err != nil {
+					t.Fatalf("unexpected error reading '%s': %v", path, err)
+				}
+				if string(b) != content {
+					t.Fatalf("unexpected content for '%s': %s", path, b)
+				}
+			}
+
+			changes, err := site.CopyDryRun(false)
+			if err != nil {
+				t.Fatalf("unexpected error from dry-run: %v", err)
+			}
+			for _, c := range changes {
+				if c.Kind != ssg.ChangeUnchanged {
+					t.Fatalf("unexpected change after copy: %+v", c)
+				}
+			}
+		})
+	}
+
+	var site Site
+	err = json.Unmarshal([]byte(`{"src": "src", "dst": "dst", "symlinks": "bad"}`), &site)
+	if err == nil {
+		t.Fatalf("expecting error from bad symlink policy")
+	}
+}
+
 func TestStages(t *testing.T) {
 	type testCase struct {
 		original Stage
@@ -0,0 +1,85 @@
+package soyweb
+
+import (
+	"github.com/gomarkdown/markdown/html"
+	"github.com/gomarkdown/markdown/parser"
+
+	"github.com/soyart/ssg/ssg-go"
+)
+
+// Markdown configures how Markdown pages of a site are converted to HTML,
+// by enabling or disabling options on top of ssg.SsgExtensions and ssg.HtmlFlags.
+// Options left unset keep their defaults. See ssg.WithMarkdown.
+type Markdown struct {
+	HardLineBreak   *bool `json:"hard-line-break"`   // Translate newlines into line breaks
+	Footnotes       *bool `json:"footnotes"`         // Pandoc-style footnotes
+	AutoHeadingIds  *bool `json:"auto-heading-ids"`  // Heading IDs from heading texts
+	Mmark           *bool `json:"mmark"`             // Mmark syntax
+	SuperSubscript  *bool `json:"super-subscript"`   // Superscripts and subscripts, e.g. 2^10^ and H~2~O
+	Smartypants     *bool `json:"smartypants"`       // Smart punctuation
+	HrefTargetBlank *bool `json:"href-target-blank"` // Open links in new tabs
+	NofollowLinks   *bool `json:"nofollow-links"`    // Add rel="nofollow" to links
+	LazyLoadImages  *bool `json:"lazy-load-images"`  // Add loading="lazy" to images
+	SkipHtml        *bool `json:"skip-html"`         // Skip raw HTML in Markdown pages
+}
+
+// fields returns pointers to options of m by their manifest keys
+func (m *Markdown) fields() map[string]**bool {
+	return map[string]**bool{
+		"hard-line-break":   &m.HardLineBreak,
+		"footnotes":         &m.Footnotes,
+		"auto-heading-ids":  &m.AutoHeadingIds,
+		"mmark":             &m.Mmark,
+		"super-subscript":   &m.SuperSubscript,
+		"smartypants":       &m.Smartypants,
+		"href-target-blank": &m.HrefTargetBlank,
+		"nofollow-links":    &m.NofollowLinks,
+		"lazy-load-images":  &m.LazyLoadImages,
+		"skip-html":         &m.SkipHtml,
+	}
+}
+
+// options returns ssg-go Markdown options of m, which may be nil
+func (m *Markdown) options() ssg.Markdown {
+	if m == nil {
+		return ssg.Markdown{}
+	}
+	extensions, flags := ssg.SsgExtensions, ssg.HtmlFlags
+	for _, e := range []struct {
+		enable    *bool
+		extension parser.Extensions
+	}{
+		{m.HardLineBreak, parser.HardLineBreak},
+		{m.Footnotes, parser.Footnotes},
+		{m.AutoHeadingIds, parser.AutoHeadingIDs},
+		{m.Mmark, parser.Mmark},
+		{m.SuperSubscript, parser.SuperSubscript},
+	} {
+		switch {
+		case e.enable == nil:
+		case *e.enable:
+			extensions |= e.extension
+		default:
+			extensions &^= e.extension
+		}
+	}
+	for _, f := range []struct {
+		enable *bool
+		flag   html.Flags
+	}{
+		{m.Smartypants, html.Smartypants},
+		{m.HrefTargetBlank, html.HrefTargetBlank},
+		{m.NofollowLinks, html.NofollowLinks},
+		{m.LazyLoadImages, html.LazyLoadImages},
+		{m.SkipHtml, html.SkipHTML},
+	} {
+		switch {
+		case f.enable == nil:
+		case *f.enable:
+			flags |= f.flag
+		default:
+			flags &^= f.flag
+		}
+	}
+	return ssg.Markdown{Extensions: extensions, Flags: flags}
+}
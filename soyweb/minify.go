@@ -16,6 +16,17 @@ import (
 
 type (
 	MinifyFn func(data []byte) ([]byte, error)
+
+	// Minify configures minifiers of a site by file extensions.
+	// Minify flags of FlagsV2, e.g. --min-css, enable minifiers for all sites,
+	// and --no-min-* flags, e.g. --no-min-css, disable them.
+	Minify struct {
+		Html     bool `json:"html"`      // Minify converted HTML outputs
+		HtmlCopy bool `json:"html-copy"` // Minify all copied HTML
+		Css      bool `json:"css"`       // Minify CSS files
+		Js       bool `json:"js"`        // Minify Javascript files
+		Json     bool `json:"json"`      // Minify JSON files
+	}
 )
 
 const (
@@ -0,0 +1,105 @@
+package soyweb
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Keys returns site keys of m in declaration order in manifest files.
+// Sites not from manifest files, e.g. unmarshaled with json.Unmarshal,
+// are sorted by their keys.
+func (m Manifest) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := m[keys[i]], m[keys[j]]
+		if a.order != b.order {
+			return a.order < b.order
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// Schedule returns site keys of m in waves, with sites in each wave depending
+// only on sites in earlier waves via DependsOn. Sites in a wave are in order of [Manifest.Keys].
+//
+// Soyweb applies waves in order, copying files of all sites in a wave before building them,
+// so that a site can copy outputs of the sites it depends on.
+// Dependencies on sites not in m, e.g. excluded by [Manifest.Select], are ignored,
+// while dependency cycles are errors.
+func (m Manifest) Schedule() ([][]string, error) {
+	keys := m.Keys()
+	pending := make(map[string]int, len(keys)) // Number of dependencies not yet scheduled
+	dependents := make(map[string][]string)
+	for _, key := range keys {
+		for _, dep := range m[key].DependsOn {
+			if _, ok := m[dep]; !ok {
+				continue
+			}
+			pending[key]++
+			dependents[dep] = append(dependents[dep], key)
+		}
+	}
+
+	var waves [][]string
+	for scheduled := 0; scheduled < len(keys); {
+		var wave []string
+		for _, key := range keys {
+			if pending[key] == 0 {
+				wave = append(wave, key)
+			}
+		}
+		if len(wave) == 0 {
+			return nil, &dependencyCycleError{keys: m.cycle(pending)}
+		}
+		for _, key := range wave {
+			pending[key] = -1 // Scheduled
+			for _, dependent := range dependents[key] {
+				pending[dependent]--
+			}
+		}
+		waves = append(waves, wave)
+		scheduled += len(wave)
+	}
+	return waves, nil
+}
+
+// dependencyCycleError is returned by Manifest.Schedule for dependency cycles
+type dependencyCycleError struct {
+	keys []string
+}
+
+func (e *dependencyCycleError) Error() string {
+	return fmt.Sprintf("dependency cycle: %s", strings.Join(e.keys, " -> "))
+}
+
+// cycle returns a dependency cycle among sites with pending dependencies
+func (m Manifest) cycle(pending map[string]int) []string {
+	var start string
+	for _, key := range m.Keys() {
+		if pending[key] > 0 {
+			start = key
+			break
+		}
+	}
+	path := []string{start}
+	for {
+		key := path[len(path)-1]
+		for _, dep := range m[key].DependsOn {
+			if pending[dep] <= 0 {
+				continue
+			}
+			i := slices.Index(path, dep)
+			if i != -1 {
+				return append(path[i:], dep)
+			}
+			path = append(path, dep)
+			break
+		}
+	}
+}
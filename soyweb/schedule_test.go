@@ -0,0 +1,124 @@
+package soyweb_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/soyart/ssg/soyweb"
+)
+
+func TestManifestSchedule(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(root, name)
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			panic(err)
+		}
+		err = os.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			panic(err)
+		}
+		return path
+	}
+	write("blog/src/index.md", "# Blog")
+	write("docs/src/index.md", "# Docs")
+	write("zine/src/index.md", "# Zine")
+
+	// Docs copies outputs of blog, which is built before docs
+	manifest := write("manifest.json", fmt.Sprintf(`{
+	"docs": {
+		"src": "%[1]s/docs/src",
+		"dst": "%[1]s/docs/dst",
+		"depends-on": ["blog"],
+		"copies": {"%[1]s/blog/dst": "%[1]s/docs/src/blog"}
+	},
+	"zine": {"src": "%[1]s/zine/src", "dst": "%[1]s/zine/dst"},
+	"blog": {"src": "%[1]s/blog/src", "dst": "%[1]s/blog/dst"}
+}`, root))
+
+	err := ValidateManifest(manifest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m, err := NewManifest(manifest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	keys := m.Keys()
+	if !reflect.DeepEqual(keys, []string{"docs", "zine", "blog"}) {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+	waves, err := m.Schedule()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(waves, [][]string{{"zine", "blog"}, {"docs"}}) {
+		t.Fatalf("unexpected waves: %v", waves)
+	}
+
+	_, err = ApplyManifestReports(m, FlagsV2{}, StageAll)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = os.Stat(filepath.Join(root, "docs/dst/blog/index.html"))
+	if err != nil {
+		t.Fatalf("missing outputs of blog in docs: %v", err)
+	}
+
+	// Dependencies on unselected sites are ignored
+	selected, err := m.Select([]string{"docs"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	waves, err = selected.Schedule()
+	if err != nil || !reflect.DeepEqual(waves, [][]string{{"docs"}}) {
+		t.Fatalf("unexpected waves of selected sites: %v %v", waves, err)
+	}
+
+	type testCase struct {
+		manifest string
+		expected []string
+	}
+	tests := []testCase{
+		{
+			manifest: `{
+	"a": {"src": "a", "dst": "A", "depends-on": ["c"]},
+	"b": {"src": "b", "dst": "B", "depends-on": ["a"]},
+	"c": {"src": "c", "dst": "C", "depends-on": ["b"]}
+}`,
+			expected: []string{":2:32: $.a.depends-on: dependency cycle: a -> c -> b -> a"},
+		},
+		{
+			manifest: `{
+	"blog": {"src": "a", "dst": "A", "depends-on": ["blgo", "blog", "nope"]}
+}`,
+			expected: []string{
+				":2:50: $.blog.depends-on[0]: unknown site 'blgo'",
+				":2:58: $.blog.depends-on[1]: site 'blog' depends on itself",
+				":2:66: $.blog.depends-on[2]: unknown site 'nope'",
+			},
+		},
+	}
+	for i := range tests {
+		tc := &tests[i]
+		path := write("bad.json", tc.manifest)
+		_, err := NewManifest(path)
+		if err == nil {
+			t.Fatalf("[%d] unexpected nil error", i)
+		}
+		lines := strings.Split(err.Error(), "\n")
+		if len(lines) != len(tc.expected) {
+			t.Fatalf("[%d] unexpected errors: %v", i, err)
+		}
+		for j := range lines {
+			if lines[j] != path+tc.expected[j] {
+				t.Fatalf("[%d] unexpected error: expecting '%s', got '%s'", i, path+tc.expected[j], lines[j])
+			}
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package soyweb
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/soyart/ssg/ssg-go"
+)
+
+// ManifestSchemaFile is the JSON Schema of manifests shipped with soyweb,
+// kept in sync with [ManifestSchema]
+const ManifestSchemaFile = "manifest.schema.json"
+
+// manifestDescriptions describes keys of sites in the manifest schema
+var manifestDescriptions = map[string]string{
+	"src":                 "Source directory of the site",
+	"dst":                 "Output directory of the site",
+	"title":               "Default title of pages",
+	"url":                 "Base URL of the site",
+	"extends":             "Key of the site to inherit options from",
+	"copies":              "Files or directories to copy, mapping copy sources to targets",
+	"cleanup":             "Remove copy targets before copying",
+	"generate-index":      "Generate index.md in directories with _index.soyweb",
+	"generate-index-mode": "Ordering of generated indexes",
+	"replaces":            "Text replacements in inputs, mapping text to replacements",
+	"symlinks":            "Symlink policy for both builds and copies",
+	"dotfiles":            "Patterns of dotfiles to build, e.g. .well-known",
+	"path-strategy":       "Output paths of Markdown pages",
+	"redirects":           "Site-wide redirects from old URL paths to new URL paths or URLs",
+	"allow-overwrites":    "Allow outputs to overwrite earlier outputs with the same targets",
+	"rewrite-links":       "Rewrite relative links to .md sources to their outputs",
+	"images":              "Image pipeline generating resized and WebP variants",
+	"fingerprint":         "Asset fingerprinting",
+	"integrity":           "Subresource Integrity attributes for scripts and stylesheets",
+	"compress":            "Precompressed .gz and .br siblings of outputs",
+	"depends-on":          "Keys of sites to apply before this site, e.g. to copy their outputs",
+	"minify":              "Minifiers by file extensions, also enabled for all sites by --min-* flags",
+	"writers":             "Number of concurrent output writers, overridden by --writers",
+	"markdown":            "Markdown extensions and HTML flags enabled or disabled on top of defaults",
+}
+
+// manifestEnums are allowed values of string keys of sites
+var manifestEnums = map[string][]string{
+	"generate-index-mode": {
+		string(IndexGeneratorModeDefault),
+		string(IndexGeneratorModeReverse), "rev", "r",
+		string(IndexGeneratorModeModTime), "updated_at", "u",
+	},
+	"symlinks":      {string(ssg.SymlinkSkip), string(ssg.SymlinkFollow), string(ssg.SymlinkCopy)},
+	"path-strategy": {string(ssg.PathsUgly), string(ssg.PathsPretty)},
+}
+
+// ManifestSchema returns the JSON Schema of manifests, generated from
+// the Go types of sites. Manifests valid against the schema pass
+// the structural checks of [NewManifest].
+func ManifestSchema() ([]byte, error) {
+	copyTarget := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"target": map[string]any{"type": "string", "description": "Copy target"},
+			"force":  map[string]any{"type": "boolean", "description": "Overwrite existing target"},
+		},
+		"required":             []string{"target"},
+		"additionalProperties": false,
+	}
+	site := schemaSite()
+	defaults := schemaSite()
+	defaults["description"] = "Options applied to all sites in this manifest"
+	delete(defaults["properties"].(map[string]any), manifestExtendsKey)
+
+	schema := map[string]any{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"title":       "soyweb manifest",
+		"description": "soyweb sites by site keys",
+		"type":        "object",
+		"properties": map[string]any{
+			manifestSchemaKey:   map[string]any{"type": "string"},
+			manifestDefaultsKey: map[string]any{"$ref": "#/$defs/defaults"},
+			manifestIncludeKey: map[string]any{
+				"description": "Other manifests to merge, relative to this manifest",
+				"oneOf": []any{
+					map[string]any{"type": "string"},
+					map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+				},
+			},
+		},
+		"additionalProperties": map[string]any{"$ref": "#/$defs/site"},
+		"$defs": map[string]any{
+			"site":     site,
+			"defaults": defaults,
+			"copyTarget": map[string]any{
+				"oneOf": []any{map[string]any{"type": "string"}, copyTarget},
+			},
+			"copyTargets": map[string]any{
+				"oneOf": []any{
+					map[string]any{"$ref": "#/$defs/copyTarget"},
+					map[string]any{"type": "array", "items": map[string]any{"$ref": "#/$defs/copyTarget"}},
+				},
+			},
+			"replaceTarget": map[string]any{
+				"oneOf": []any{
+					map[string]any{"type": "string"},
+					map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"text":  map[string]any{"type": "string", "description": "Replacement text"},
+							"count": map[string]any{"type": "integer", "minimum": 0, "description": "Number of replacements, 0 replaces all"},
+						},
+						"required":             []string{"text", "count"},
+						"additionalProperties": false,
+					},
+				},
+			},
+		},
+	}
+
+	b, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+func schemaSite() map[string]any {
+	t := reflect.TypeOf(manifestSite{})
+	properties := make(map[string]any)
+	for i := 0; i < t.NumField(); i++ {
+		key := jsonKey(t.Field(i))
+		if key == "" {
+			continue
+		}
+		property := schemaOf(t.Field(i).Type)
+		if desc, ok := manifestDescriptions[key]; ok {
+			property["description"] = desc
+		}
+		if enum, ok := manifestEnums[key]; ok {
+			property["enum"] = enum
+		}
+		properties[key] = property
+	}
+	// src and dst are not required here, as sites may inherit them
+	// with extends or from defaults, which the schema cannot follow
+	return map[string]any{
+		"type":                 "object",
+		"description":          "Site with src and dst, which may be inherited with extends or from defaults",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
+// schemaOf returns JSON Schema of values of Go type t, like validator.check
+func schemaOf(t reflect.Type) map[string]any {
+	switch t {
+	case reflect.TypeOf(CopyTargets{}):
+		return map[string]any{"$ref": "#/$defs/copyTargets"}
+	case reflect.TypeOf(ReplaceTarget{}):
+		return map[string]any{"$ref": "#/$defs/replaceTarget"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaOf(t.Elem())
+
+	case reflect.Struct:
+		properties := make(map[string]any)
+		for i := 0; i < t.NumField(); i++ {
+			key := jsonKey(t.Field(i))
+			if key == "" {
+				continue
+			}
+			properties[key] = schemaOf(t.Field(i).Type)
+		}
+		return map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaOf(t.Elem())}
+
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": schemaOf(t.Elem())}
+
+	case reflect.String:
+		return map[string]any{"type": "string"}
+
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	}
+	return map[string]any{}
+}
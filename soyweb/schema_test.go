@@ -0,0 +1,67 @@
+package soyweb_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/soyart/ssg/soyweb"
+)
+
+func TestManifestSchema(t *testing.T) {
+	schema, err := ManifestSchema()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	shipped, err := os.ReadFile(ManifestSchemaFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(schema, shipped) {
+		t.Fatalf("%s is out of sync with Go types, regenerate it with 'soyweb schema > %s'", ManifestSchemaFile, ManifestSchemaFile)
+	}
+
+	var parsed struct {
+		Defs struct {
+			Site struct {
+				Properties map[string]map[string]any `json:"properties"`
+				Required   []string                  `json:"required"`
+			} `json:"site"`
+		} `json:"$defs"`
+	}
+	err = json.Unmarshal(schema, &parsed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(parsed.Defs.Site.Properties) == 0 {
+		t.Fatal("missing site properties")
+	}
+	// Sites may inherit src and dst
+	if len(parsed.Defs.Site.Required) != 0 {
+		t.Fatalf("unexpected required site keys: %v", parsed.Defs.Site.Required)
+	}
+	for key, property := range parsed.Defs.Site.Properties {
+		if property["description"] == nil {
+			t.Fatalf("missing description of site key '%s'", key)
+		}
+	}
+
+	// Manifests can point editors to the schema
+	manifest := filepath.Join(t.TempDir(), "manifest.json")
+	err = os.WriteFile(manifest, []byte(`{
+	"$schema": "./manifest.schema.json",
+	"site": {"src": "src", "dst": "dst"}
+}`), 0644)
+	if err != nil {
+		panic(err)
+	}
+	m, err := NewManifest(manifest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(m) != 1 {
+		t.Fatalf("unexpected sites: %+v", m)
+	}
+}
@@ -2,8 +2,8 @@ package soyweb
 
 import (
 	"errors"
+	"io"
 	"log/slog"
-	"os"
 
 	"github.com/soyart/ssg/ssg-go"
 )
@@ -24,19 +24,37 @@ var ErrWebFormatNotSupported = errors.New("unsupported web format")
 // by ordering hooks and pipeline
 type builder struct {
 	Site
-	flags FlagsV2
+	flags  FlagsV2
+	images *images
 }
 
 func newManifestBuilder(s Site, f FlagsV2) *builder {
-	b := &builder{Site: s, flags: f}
+	// Build options of the site are resolved into flags,
+	// with flags from CLI taking precedence
+	b := &builder{Site: s, flags: f.site(s)}
+	b.Markdown, _ = f.markdown(s.Markdown) // Flags are validated by ApplyManifestReports
+	if s.Images != nil && !f.NoBuild {
+		b.images = newImages(&b.ssg, *s.Images, f.DryRun)
+	}
 	b.initialize()
 	return b
 }
 
 func (b *builder) initialize() {
 	b.ssg.With(
+		ssg.WithLogger(b.logger()),
+		ssg.WithSymlinks(b.Symlinks),
+		ssg.AllowDotfiles(b.Dotfiles...),
+		ssg.WithPathStrategy(b.PathStrategy),
+		ssg.WithRedirects(b.Redirects),
+		ssg.AllowOverwrites(b.AllowOverwrites),
+		ssg.RewriteLinks(b.RewriteLinks),
+		ssg.WithCompression(b.Compression),
+		ssg.Writers(b.flags.Writers),
+		ssg.WithMarkdown(b.Markdown.options()),
 		ssg.WithHooks(b.Hooks()...),
 		ssg.WithHooksGenerate(b.HooksGenerate()...),
+		ssg.WithHooksOutputs(b.HooksOutputs()...),
 		ssg.WithPipelines(b.Pipelines()...),
 	)
 }
@@ -65,19 +83,43 @@ func (b *builder) HooksGenerate() []ssg.HookGenerate {
 	return nil
 }
 
+func (b *builder) HooksOutputs() []ssg.HookOutputs {
+	if b.flags.NoBuild {
+		return nil
+	}
+	var hooks []ssg.HookOutputs
+	if b.images != nil {
+		// Images go first, so that fingerprints see rewritten pages
+		hooks = append(hooks, b.images.hookOutputs)
+	}
+	if b.Fingerprint != nil {
+		hooks = append(hooks, HookFingerprint(&b.ssg, *b.Fingerprint))
+	}
+	if b.Integrity != nil {
+		// Integrity goes last to hash the final outputs
+		hooks = append(hooks, HookIntegrity(&b.ssg, *b.Integrity))
+	}
+	return hooks
+}
+
 func (b *builder) Pipelines() []any {
+	var pipelines []any
+	if b.images != nil {
+		// Image pipeline goes first to see every file built
+		pipelines = append(pipelines, ssg.Pipeline(b.images.pipeline))
+	}
 	if b.flags.NoGenerateIndex || !b.GenerateIndex {
-		return nil
+		return pipelines
 	}
-	return []any{
+	return append(pipelines,
 		NewIndexGenerator(b.GenerateIndexMode)(&b.ssg),
-	}
+	)
 }
 
-func newLogger() *slog.Logger {
+func newLogger(w io.Writer) *slog.Logger {
 	loglevel.Set(slog.LevelDebug)
 	return slog.New(slog.NewJSONHandler(
-		os.Stdout,
+		w,
 		&slog.HandlerOptions{
 			AddSource: true,
 			Level:     loglevel,
@@ -0,0 +1,76 @@
+package soyweb
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/soyart/ssg/ssg-go"
+)
+
+// FprintSummary prints a table of failures in err returned by [ApplyManifestReports],
+// with stages and keys of the failed sites, e.g. with [FlagsV2.ContinueOnError]:
+//
+//	STAGE  SITE  ERROR
+//	build  blog  failed to build: ...
+//	build  docs  skipped, depends on failed site 'blog'
+//
+// Errors from loading manifests, e.g. with [NewManifest], are listed in stage load.
+func FprintSummary(w io.Writer, err error) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	ssg.Fprintln(tw, "STAGE\tSITE\tERROR")
+	for _, e := range unjoin(err) {
+		stage, key, msg := "-", "-", e.Error()
+		var errSite manifestError
+		var errManifest *ManifestError
+		switch {
+		case errors.As(e, &errSite):
+			stage, key, msg = errSite.stage.String(), errSite.key, errSite.detail()
+		case errors.As(e, &errManifest):
+			stage, key = "load", siteOfPath(errManifest.Path)
+		}
+		ssg.Fprintf(tw, "%s\t%s\t%s\n", stage, key, strings.ReplaceAll(msg, "\n", "; "))
+	}
+	tw.Flush()
+}
+
+// siteOfPath returns key of the site at JSON path p of a manifest,
+// e.g. blog for $.blog.copies, or "-" if p is not in a site
+func siteOfPath(p string) string {
+	rest, ok := strings.CutPrefix(p, "$")
+	switch {
+	case !ok:
+	case strings.HasPrefix(rest, "."):
+		key, _, _ := strings.Cut(rest[1:], ".")
+		key, _, _ = strings.Cut(key, "[")
+		return key
+	case strings.HasPrefix(rest, "["):
+		quoted, err := strconv.QuotedPrefix(rest[1:])
+		if err != nil {
+			break
+		}
+		key, err := strconv.Unquote(quoted)
+		if err == nil {
+			return key
+		}
+	}
+	return "-"
+}
+
+// unjoin returns errors joined in err, e.g. with errors.Join
+func unjoin(err error) []error {
+	if err == nil {
+		return nil
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{err}
+	}
+	var errs []error
+	for _, e := range joined.Unwrap() {
+		errs = append(errs, unjoin(e)...)
+	}
+	return errs
+}
@@ -0,0 +1,683 @@
+package soyweb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/soyart/ssg/ssg-go"
+)
+
+// ManifestError is an error in a manifest file, located by its JSON path
+// and its line and column in the file. Line and column are 0 if unknown.
+type ManifestError struct {
+	File   string
+	Path   string // JSON path, e.g. $["example.com"].copies
+	Line   int
+	Column int
+	Err    error
+}
+
+// manifestSchemaKey is the top-level key for JSON Schema of manifests,
+// which is not a site
+const manifestSchemaKey = "$schema"
+
+// manifestSiteRequired are keys required in every site
+var manifestSiteRequired = []string{"src", "dst"}
+
+var reIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// node is a JSON value with its position, used to validate manifests
+type node struct {
+	file   string
+	line   int
+	column int
+	path   string
+
+	value  any // Scalars: string, json.Number, bool or nil
+	object bool
+	keys   []string // Object keys in order
+	fields map[string]*node
+	items  []*node // Array items, nil for non-arrays
+}
+
+// validator collects errors in a manifest
+type validator struct {
+	file   string
+	copies bool // Check that copy sources exist
+	errs   []*ManifestError
+}
+
+func (e *ManifestError) Error() string {
+	location := e.File
+	if e.Line != 0 {
+		location = fmt.Sprintf("%s:%d", location, e.Line)
+	}
+	if e.Column != 0 {
+		location = fmt.Sprintf("%s:%d", location, e.Column)
+	}
+	if e.Path == "" {
+		return fmt.Sprintf("%s: %s", location, e.Err.Error())
+	}
+	return fmt.Sprintf("%s: %s: %s", location, e.Path, e.Err.Error())
+}
+
+func (e *ManifestError) Unwrap() error { return e.Err }
+
+// ValidateManifest strictly validates manifest file like [NewManifest],
+// and also checks that sources of copies exist, without building anything.
+// All errors found are returned joined, each as a [*ManifestError].
+func ValidateManifest(filename string) error {
+	return ValidateManifestVars(filename, nil)
+}
+
+// ValidateManifestVars is like [ValidateManifest],
+// with variables for ${var:NAME} interpolation. See [NewManifestVars].
+func ValidateManifestVars(filename string, vars map[string]string) error {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("failed to read manifest from file '%s': %w", filename, err)
+	}
+	_, err = parseManifest(filename, b, vars, true)
+	return err
+}
+
+// parseManifest strictly validates and parses manifest data b read from filename,
+// which may be JSON, YAML or TOML. See [ManifestFormat].
+func parseManifest(filename string, b []byte, vars map[string]string, copies bool) (Manifest, error) {
+	root, err := (&loader{vars: vars}).load(filename, b)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &validator{file: filename, copies: copies}
+	v.manifest(root)
+	if len(v.errs) != 0 {
+		return nil, v.err()
+	}
+
+	// Sites are unmarshaled one by one to locate their errors
+	m := make(Manifest)
+	for _, key := range root.keys {
+		if manifestReserved(key) {
+			continue
+		}
+		site := root.fields[key]
+		raw, err := json.Marshal(site.raw())
+		if err != nil {
+			return nil, err
+		}
+		var s Site
+		err = json.Unmarshal(raw, &s)
+		if err != nil {
+			v.errorf(site, "%s", err.Error())
+			continue
+		}
+		s.order = len(m)
+		m[key] = s
+	}
+	if len(v.errs) != 0 {
+		return nil, v.err()
+	}
+
+	_, err = m.Schedule()
+	var errCycle *dependencyCycleError
+	if errors.As(err, &errCycle) {
+		site := root.fields[errCycle.keys[0]]
+		if deps := site.fields["depends-on"]; deps != nil {
+			site = deps
+		}
+		v.errorf(site, "%s", err.Error())
+		return nil, v.err()
+	}
+	return m, nil
+}
+
+func (v *validator) manifest(root *node) {
+	if !root.object {
+		v.errorf(root, "expecting manifest object of sites, got %s", root.kind())
+		return
+	}
+	t := reflect.TypeOf(manifestSite{})
+	for _, key := range root.keys {
+		site := root.fields[key]
+		switch key {
+		case manifestSchemaKey:
+			v.expect(site, "string")
+			continue
+		case manifestIncludeKey:
+			continue // Checked by loader
+		case manifestDefaultsKey:
+			if v.expect(site, "object") {
+				if extends := site.fields[manifestExtendsKey]; extends != nil {
+					v.errorf(extends, "extends is not allowed in defaults")
+				}
+				v.check(site, t)
+			}
+			continue
+		}
+		if !site.object {
+			v.errorf(site, "expecting site object, got %s", site.kind())
+			continue
+		}
+		for _, required := range manifestSiteRequired {
+			if site.fields[required] == nil {
+				v.errorf(site, "missing required key '%s'", required)
+			}
+		}
+		v.check(site, t)
+		v.dependencies(root, key, site)
+		v.paths(site)
+		if v.copies {
+			v.copySources(root, site)
+		}
+	}
+}
+
+// dependencies checks that site key depends on other sites in root
+func (v *validator) dependencies(root *node, key string, site *node) {
+	deps := site.fields["depends-on"]
+	if deps == nil {
+		return
+	}
+	var keys []string
+	for _, k := range root.keys {
+		if k != key && !manifestReserved(k) {
+			keys = append(keys, k)
+		}
+	}
+	for _, dep := range deps.items {
+		name, ok := dep.value.(string)
+		switch {
+		case !ok:
+			continue // Reported by check
+		case name == key:
+			v.errorf(dep, "site '%s' depends on itself", key)
+		case root.fields[name] == nil || manifestReserved(name):
+			v.unknown(dep, "site", name, keys)
+		}
+	}
+}
+
+// paths checks that src and dst of site differ,
+// and that .ssgignore under src can be parsed
+func (v *validator) paths(site *node) {
+	srcNode, dstNode := site.fields["src"], site.fields["dst"]
+	if srcNode == nil || dstNode == nil {
+		return
+	}
+	src, ok := srcNode.value.(string)
+	if !ok {
+		return // Reported by check
+	}
+	dst, ok := dstNode.value.(string)
+	if !ok {
+		return
+	}
+	if filepath.Clean(src) == filepath.Clean(dst) {
+		v.errorf(dstNode, "src is identical to dst '%s'", dst)
+		return
+	}
+	_, err := ssg.ParseSsgIgnore(filepath.Join(src, ssg.SsgIgnore))
+	if err != nil {
+		v.errorf(srcNode, "%s", err.Error())
+	}
+}
+
+// check validates n against Go type t of manifestSite fields
+func (v *validator) check(n *node, t reflect.Type) {
+	switch t {
+	case reflect.TypeOf(CopyTargets{}):
+		v.copyTargets(n, true)
+		return
+	case reflect.TypeOf(ReplaceTarget{}):
+		v.replaceTarget(n)
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		if n.value == nil && !n.object && n.items == nil {
+			return
+		}
+		v.check(n, t.Elem())
+
+	case reflect.Struct:
+		if !v.expect(n, "object") {
+			return
+		}
+		known := make(map[string]reflect.Type)
+		var keys []string
+		for i := 0; i < t.NumField(); i++ {
+			key := jsonKey(t.Field(i))
+			if key == "" {
+				continue
+			}
+			known[key] = t.Field(i).Type
+			keys = append(keys, key)
+		}
+		for _, key := range n.keys {
+			field, ok := known[key]
+			if !ok {
+				v.unknown(n.fields[key], "key", key, keys)
+				continue
+			}
+			v.check(n.fields[key], field)
+		}
+
+	case reflect.Map:
+		if !v.expect(n, "object") {
+			return
+		}
+		for _, key := range n.keys {
+			v.check(n.fields[key], t.Elem())
+		}
+
+	case reflect.Slice:
+		if !v.expect(n, "array") {
+			return
+		}
+		for _, item := range n.items {
+			v.check(item, t.Elem())
+		}
+
+	case reflect.String:
+		v.expect(n, "string")
+
+	case reflect.Bool:
+		v.expect(n, "boolean")
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.expect(n, "integer")
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.expect(n, "integer") && strings.HasPrefix(n.value.(json.Number).String(), "-") {
+			v.errorf(n, "expecting non-negative integer")
+		}
+
+	case reflect.Float32, reflect.Float64:
+		v.expect(n, "number")
+	}
+}
+
+// copyTargets validates copy targets, which are a string,
+// an object with target and force, or an array of those
+func (v *validator) copyTargets(n *node, array bool) {
+	switch {
+	case n.items != nil && array:
+		for _, item := range n.items {
+			v.copyTargets(item, false)
+		}
+		return
+
+	case n.object:
+		v.object(n, map[string]string{"target": "string", "force": "boolean"}, "target")
+		return
+	}
+	v.expect(n, "string")
+}
+
+// replaceTarget validates replace targets, which are
+// a string or an object with text and count
+func (v *validator) replaceTarget(n *node) {
+	if n.object {
+		v.object(n, map[string]string{"text": "string", "count": "integer"}, "text", "count")
+		return
+	}
+	v.expect(n, "string")
+}
+
+// object validates object n with known keys of kinds, and required keys
+func (v *validator) object(n *node, kinds map[string]string, required ...string) {
+	keys := make([]string, 0, len(kinds))
+	for key := range kinds {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range n.keys {
+		kind, ok := kinds[key]
+		if !ok {
+			v.unknown(n.fields[key], "key", key, keys)
+			continue
+		}
+		v.expect(n.fields[key], kind)
+	}
+	for _, key := range required {
+		if n.fields[key] == nil {
+			v.errorf(n, "missing required key '%s'", key)
+		}
+	}
+}
+
+// expect reports an error if n is not of kind
+func (v *validator) expect(n *node, kind string) bool {
+	if n.kind() == kind || (kind == "number" && n.kind() == "integer") {
+		return true
+	}
+	v.errorf(n, "expecting %s, got %s", kind, n.kind())
+	return false
+}
+
+// unknown reports unknown key of kind, e.g. key or site,
+// with suggestion from known keys
+func (v *validator) unknown(n *node, kind, key string, known []string) {
+	suggestion := suggest(key, known)
+	if suggestion == "" {
+		v.errorf(n, "unknown %s '%s'", kind, key)
+		return
+	}
+	v.errorf(n, "unknown %s '%s', did you mean '%s'?", kind, key, suggestion)
+}
+
+// copySources checks that sources of copies in site exist,
+// except for sources in dst of sites it depends on, which are built first
+func (v *validator) copySources(root *node, site *node) {
+	copies := site.fields["copies"]
+	if copies == nil || !copies.object {
+		return
+	}
+	var built []string
+	if deps := site.fields["depends-on"]; deps != nil {
+		for _, dep := range deps.items {
+			name, _ := dep.value.(string)
+			if root.fields[name] == nil || root.fields[name].fields["dst"] == nil {
+				continue
+			}
+			if dst, ok := root.fields[name].fields["dst"].value.(string); ok {
+				built = append(built, dst)
+			}
+		}
+	}
+	for _, src := range copies.keys {
+		_, err := os.Stat(src)
+		if errors.Is(err, fs.ErrNotExist) && slices.ContainsFunc(built, func(dst string) bool { return within(dst, src) }) {
+			continue
+		}
+		if errors.Is(err, fs.ErrNotExist) {
+			v.errorf(copies.fields[src], "nonexistent copy source '%s'", src)
+			continue
+		}
+		if err != nil {
+			v.errorf(copies.fields[src], "bad copy source '%s': %s", src, err.Error())
+		}
+	}
+}
+
+func (v *validator) errorf(n *node, format string, args ...any) {
+	err := n.errorf(format, args...)
+	if err.File == "" {
+		err.File = v.file
+	}
+	v.errs = append(v.errs, err)
+}
+
+// err returns all errors sorted by their positions. Errors in values
+// inherited by multiple sites are only returned once.
+func (v *validator) err() error {
+	sort.SliceStable(v.errs, func(i, j int) bool {
+		if v.errs[i].Line != v.errs[j].Line {
+			return v.errs[i].Line < v.errs[j].Line
+		}
+		return v.errs[i].Column < v.errs[j].Column
+	})
+	seen := make(map[string]bool)
+	errs := make([]error, 0, len(v.errs))
+	for _, err := range v.errs {
+		if seen[err.Error()] {
+			continue
+		}
+		seen[err.Error()] = true
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func (n *node) kind() string {
+	switch {
+	case n.object:
+		return "object"
+	case n.items != nil:
+		return "array"
+	}
+	switch value := n.value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		_, err := value.Int64()
+		if err == nil {
+			return "integer"
+		}
+		return "number"
+	}
+	return "null"
+}
+
+// raw returns n as Go value to be marshaled again
+func (n *node) raw() any {
+	switch {
+	case n.object:
+		m := make(map[string]any, len(n.keys))
+		for _, key := range n.keys {
+			m[key] = n.fields[key].raw()
+		}
+		return m
+	case n.items != nil:
+		items := make([]any, len(n.items))
+		for i := range n.items {
+			items[i] = n.items[i].raw()
+		}
+		return items
+	}
+	return n.value
+}
+
+// parseNode parses JSON data b into nodes with positions.
+// Duplicate keys are errors.
+func parseNode(b []byte) (*node, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	p := &nodeParser{b: b, dec: dec}
+	root, err := p.parse("$")
+	if err != nil {
+		return nil, err
+	}
+	_, err = dec.Token()
+	if !errors.Is(err, io.EOF) {
+		return nil, &json.SyntaxError{Offset: dec.InputOffset()}
+	}
+	return root, nil
+}
+
+type nodeParser struct {
+	b   []byte
+	dec *json.Decoder
+}
+
+// duplicateKeyError is returned by nodeParser for duplicate object keys
+type duplicateKeyError struct {
+	key    string
+	path   string
+	offset int64
+}
+
+func (e *duplicateKeyError) Error() string {
+	return fmt.Sprintf("duplicate key '%s'", e.key)
+}
+
+func (p *nodeParser) parse(path string) (*node, error) {
+	offset := p.start()
+	token, err := p.dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	n := &node{path: path}
+	n.line, n.column = position(p.b, offset)
+
+	switch token {
+	case json.Delim('{'):
+		n.object = true
+		n.fields = make(map[string]*node)
+		for p.dec.More() {
+			offset := p.start()
+			token, err := p.dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key := token.(string)
+			if n.fields[key] != nil {
+				return nil, &duplicateKeyError{key: key, path: path + pathKey(key), offset: offset}
+			}
+			field, err := p.parse(path + pathKey(key))
+			if err != nil {
+				return nil, err
+			}
+			// Errors about keys point to the keys
+			field.line, field.column = position(p.b, offset)
+			n.keys = append(n.keys, key)
+			n.fields[key] = field
+		}
+		_, err = p.dec.Token()
+
+	case json.Delim('['):
+		n.items = []*node{}
+		for i := 0; p.dec.More(); i++ {
+			item, err := p.parse(fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			n.items = append(n.items, item)
+		}
+		_, err = p.dec.Token()
+
+	default:
+		n.value = token
+	}
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// start returns offset of the next token
+func (p *nodeParser) start() int64 {
+	offset := p.dec.InputOffset()
+	for offset < int64(len(p.b)) {
+		switch p.b[offset] {
+		case ' ', '\t', '\r', '\n', ',', ':':
+			offset++
+			continue
+		}
+		break
+	}
+	return offset
+}
+
+// positionError converts JSON parse errors of b to a *ManifestError
+func positionError(filename string, b []byte, err error) error {
+	var (
+		errSyntax *json.SyntaxError
+		errDup    *duplicateKeyError
+		offset    = int64(len(b))
+		path      string
+	)
+	switch {
+	case errors.As(err, &errSyntax):
+		offset = errSyntax.Offset
+	case errors.As(err, &errDup):
+		offset, path = errDup.offset, errDup.path
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		err = errors.New("unexpected end of JSON input")
+	}
+	line, column := position(b, offset)
+	return &ManifestError{File: filename, Path: path, Line: line, Column: column, Err: err}
+}
+
+// position returns 1-based line and column of offset in b
+func position(b []byte, offset int64) (int, int) {
+	if offset > int64(len(b)) {
+		offset = int64(len(b))
+	}
+	before := b[:offset]
+	line := bytes.Count(before, []byte{'\n'}) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
+// within reports whether path is dir or is under dir
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// pathKey returns JSON path segment for object key
+func pathKey(key string) string {
+	if reIdentifier.MatchString(key) {
+		return "." + key
+	}
+	return "[" + strconv.Quote(key) + "]"
+}
+
+// jsonKey returns JSON key of struct field f, or an empty string if f is not in JSON
+func jsonKey(f reflect.StructField) string {
+	if !f.IsExported() {
+		return ""
+	}
+	key, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	switch key {
+	case "-":
+		return ""
+	case "":
+		return f.Name
+	}
+	return key
+}
+
+// suggest returns the known key closest to key, or an empty string
+// if no known keys are close enough
+func suggest(key string, known []string) string {
+	normalize := func(s string) string {
+		return strings.ReplaceAll(strings.ToLower(s), "_", "-")
+	}
+	best, closest := "", 3
+	for _, k := range known {
+		d := distance(normalize(key), normalize(k))
+		if d < closest && d < len(k) {
+			best, closest = k, d
+		}
+	}
+	return best
+}
+
+// distance returns the Levenshtein distance between a and b
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
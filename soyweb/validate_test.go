@@ -0,0 +1,192 @@
+package soyweb_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/soyart/ssg/soyweb"
+)
+
+func TestValidateManifest(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(root, name)
+		err := os.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			panic(err)
+		}
+		return path
+	}
+
+	valid := write("valid.json", `{
+	"example.com": {
+		"src": "example.com/src",
+		"dst": "example.com/dst",
+		"copies": {
+			"`+root+`": ["example.com/src/a", {"target": "example.com/src/b", "force": true}]
+		},
+		"replaces": {"foo": {"text": "bar", "count": 1}},
+		"images": {"widths": [480]}
+	}
+}`)
+	_, err := NewManifest(valid)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = ValidateManifest(valid)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	badIgnore := filepath.Join(root, "bad-ignore")
+	err = os.MkdirAll(badIgnore, 0755)
+	if err != nil {
+		panic(err)
+	}
+	write(filepath.Join("bad-ignore", ".ssgignore"), "drafts/\n[abc\n")
+
+	type testCase struct {
+		manifest string
+		expected []string // Expected error messages, in order
+	}
+
+	tests := []testCase{
+		{
+			manifest: `{
+	"example.com": {
+		"src": "src",
+		"titel": "Example",
+		"generate_index": true,
+		"name": "Example",
+		"cleanup": "yes",
+		"images": {"widths": [480.5], "qualty": 80},
+		"copies": {"assets": {"force": true}},
+		"replaces": {"foo": {"text": "bar", "count": -1}}
+	}
+}`,
+			expected: []string{
+				`2:2: $["example.com"]: missing required key 'dst'`,
+				`4:3: $["example.com"].titel: unknown key 'titel', did you mean 'title'?`,
+				`5:3: $["example.com"].generate_index: unknown key 'generate_index', did you mean 'generate-index'?`,
+				`6:3: $["example.com"].name: unknown key 'name'`,
+				`7:3: $["example.com"].cleanup: expecting boolean, got string`,
+				`8:25: $["example.com"].images.widths[0]: expecting integer, got number`,
+				`8:33: $["example.com"].images.qualty: unknown key 'qualty', did you mean 'quality'?`,
+				`9:14: $["example.com"].copies.assets: missing required key 'target'`,
+			},
+		},
+		{
+			manifest: `{
+	"site": {
+		"src": "src",
+		"dst": "dst",
+		"path-strategy": "handsome"
+	}
+}`,
+			expected: []string{`2:2: $.site: `},
+		},
+		{
+			manifest: `{
+	"site": {
+		"src": "src",
+		"dst": "dst",
+		"writers": -1,
+		"minify": {"css": true, "scss": true},
+		"markdown": {"hard-line-breaks": true, "smartypants": "no"}
+	}
+}`,
+			expected: []string{
+				`5:3: $.site.writers: expecting non-negative integer`,
+				`6:27: $.site.minify.scss: unknown key 'scss', did you mean 'css'?`,
+				`7:16: $.site.markdown.hard-line-breaks: unknown key 'hard-line-breaks', did you mean 'hard-line-break'?`,
+				`7:42: $.site.markdown.smartypants: expecting boolean, got string`,
+			},
+		},
+		{
+			manifest: `{
+	"site": {"src": "src", "dst": "dst"},
+	"site": {"src": "src", "dst": "dst"}
+}`,
+			expected: []string{`3:2: $.site: duplicate key 'site'`},
+		},
+		{
+			manifest: `{
+	"site": {
+		"src": "src",
+		"dst": "dst",
+	}
+}`,
+			expected: []string{`4:16: invalid character ','`},
+		},
+		{
+			manifest: `{
+	"site": {
+		"src": "site",
+		"dst": "./site/"
+	}
+}`,
+			expected: []string{`4:3: $.site.dst: src is identical to dst './site/'`},
+		},
+		{
+			manifest: `{
+	"site": {
+		"src": "` + badIgnore + `",
+		"dst": "dst"
+	}
+}`,
+			expected: []string{`3:3: $.site.src: bad pattern at ` + filepath.Join(badIgnore, ".ssgignore") + `:2: unclosed bracket`},
+		},
+		{
+			manifest: `["site"]`,
+			expected: []string{`1:1: $: expecting manifest object of sites, got array`},
+		},
+	}
+
+	for i := range tests {
+		tc := &tests[i]
+		path := write("manifest.json", tc.manifest)
+		_, err := NewManifest(path)
+		if err == nil {
+			t.Fatalf("[%d] expecting errors", i)
+		}
+		lines := strings.Split(err.Error(), "\n")
+		if len(lines) != len(tc.expected) {
+			t.Fatalf("[%d] unexpected errors:\n%v", i, err)
+		}
+		for j, expected := range tc.expected {
+			if !strings.HasPrefix(lines[j], path+":"+expected) {
+				t.Fatalf("[%d] unexpected error %d: expecting '%s', got '%s'", i, j, expected, lines[j])
+			}
+		}
+		var errManifest *ManifestError
+		if !errors.As(err, &errManifest) || errManifest.File != path {
+			t.Fatalf("[%d] unexpected error type: %v", i, err)
+		}
+	}
+
+	missing := write("missing.json", `{"site": {"src": "src", "dst": "dst", "copies": {"nonexistent/file": "src/file"}}}`)
+	_, err = NewManifest(missing)
+	if err != nil {
+		t.Fatalf("unexpected error from NewManifest: %v", err)
+	}
+	err = ValidateManifest(missing)
+	if err == nil || !strings.Contains(err.Error(), `1:50: $.site.copies["nonexistent/file"]: nonexistent copy source 'nonexistent/file'`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Sites unmarshaled without validation also return errors instead of panicking
+	for _, site := range []string{
+		`{"src": "site", "dst": "site"}`,
+		`{"src": "` + badIgnore + `", "dst": "dst"}`,
+	} {
+		var s Site
+		err = json.Unmarshal([]byte(site), &s)
+		if err == nil {
+			t.Fatalf("expecting error from site %s", site)
+		}
+	}
+}
@@ -5,35 +5,104 @@ import (
 	"fmt"
 	"io/fs"
 	"path/filepath"
+	"time"
 )
 
 func build(s *Ssg, o Outputs) ([]string, []OutputFile, error) {
+	return buildReport(s, o, newReport(s))
+}
+
+// buildReport builds s with report r, which may be shared with writers
+func buildReport(s *Ssg, o Outputs, r *Report) ([]string, []OutputFile, error) {
+	start := time.Now()
+	s.report = r
+	s.pages = make(map[string]string)
+	s.redirects = nil
 	s.result = buildOutput{
 		cacheOutput: s.options.caching,
+		overwrite:   s.options.overwrite,
+		compression: s.options.compression,
 		writer:      o,
 	}
+	s.result.reserve(metadataTargets(s.Dst)...)
+	// Hold all outputs until the build is done if output hooks may change them,
+	// or if outputs may be overwritten, so that only the last output
+	// for each target is written
+	hold := len(s.options.hookOutputs) != 0 || s.options.overwrite
+	if hold {
+		s.result.cacheOutput, s.result.writer = true, nil
+	}
 	err := filepath.WalkDir(s.Src, s.walk)
+	if err == nil {
+		err = s.buildRedirects()
+	}
+	if err == nil {
+		err = s.result.err()
+	}
+	if err == nil && hold {
+		err = s.flush(o)
+	}
+	r.Timings.Build = time.Since(start)
 	if err != nil {
 		return nil, nil, err
 	}
 	return s.result.files, s.result.cache, nil
 }
 
+// flush calls output hooks with all outputs held in s.result,
+// and adds their results to o, registering their targets again
+func (s *Ssg) flush(o Outputs) error {
+	outputs := s.result.cache
+	for i, hook := range s.options.hookOutputs {
+		start := time.Now()
+		var err error
+		outputs, err = hook(outputs)
+		s.report.Timings.HooksOutputs[i] += time.Since(start)
+		if err != nil {
+			return fmt.Errorf("hooksOutputs[%d] error: %w", i, err)
+		}
+	}
+
+	s.result.cache, s.result.targets, s.result.siblings = nil, nil, nil
+	s.result.cacheOutput, s.result.writer = s.options.caching, o
+	s.result.Add(outputs...)
+	return s.result.err()
+}
+
+// addMetadata registers metadata outputs of the last build, e.g. sitemap.xml,
+// so that they never silently overwrite other outputs. The metadata
+// is only cached in s.result, and is to be written by the caller.
+func (s *Ssg) addMetadata(metadata []OutputFile) error {
+	s.result.writer, s.result.reserved = nil, nil
+	s.result.Add(metadata...)
+	return s.result.err()
+}
+
 func (s *Ssg) walk(path string, d fs.DirEntry, err error) error {
 	if err != nil {
 		return err
 	}
-	if d.IsDir() {
+	if d.IsDir() && path == s.Src {
 		return s.collect(path)
 	}
 
 	base := filepath.Base(path)
-	ignore, err := shouldIgnore(s.ssgignores, path, base, d)
+	reason, rule, err := s.shouldIgnore(path, base, d.IsDir())
+	if reason != "" {
+		s.report.skip(path, reason, rule)
+		return err
+	}
 	if err != nil {
 		return err
 	}
-	if ignore {
-		return nil
+	if d.IsDir() {
+		return s.collect(path)
+	}
+	if d.Type()&fs.ModeSymlink != 0 {
+		d, err = s.symlink(path)
+		if err != nil || d == nil {
+			return err
+		}
 	}
 
 	switch base {
@@ -42,6 +111,7 @@ func (s *Ssg) walk(path string, d fs.DirEntry, err error) error {
 		MarkerFooter,
 		SsgIgnore:
 
+		s.report.skip(path, SkipReasonMarker, nil)
 		return nil
 	}
 
@@ -55,10 +125,13 @@ func (s *Ssg) walk(path string, d fs.DirEntry, err error) error {
 	// Original ssg does not include _header.html
 	// and _footer.html in .files
 	s.result.files = append(s.result.files, path)
+	s.report.input(path)
 
 	skipCore := false
 	for i, p := range s.options.pipelines {
+		start := time.Now()
 		path, data, d, err = p(path, data, d)
+		s.report.Timings.Pipelines[i] += time.Since(start)
 		if err == nil {
 			continue
 		}
@@ -73,6 +146,7 @@ func (s *Ssg) walk(path string, d fs.DirEntry, err error) error {
 	}
 
 	if skipCore {
+		s.report.skip(path, SkipReasonSkipCore, nil)
 		return nil
 	}
 
@@ -81,5 +155,5 @@ func (s *Ssg) walk(path string, d fs.DirEntry, err error) error {
 		return fmt.Errorf("core error: %w", err)
 	}
 	s.result.Add(output)
-	return nil
+	return s.result.err()
 }
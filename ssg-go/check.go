@@ -0,0 +1,236 @@
+package ssg
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	LinkMissing       = "missing"        // Link to a file that does not exist in dst
+	LinkMissingAnchor = "missing-anchor" // Link to an anchor that does not exist in the linked page
+	LinkMarkdown      = "markdown"       // Link to a Markdown file, usually meant to be a link to its HTML output
+)
+
+// BrokenLink is a broken link found in an HTML output by [Check]
+type BrokenLink struct {
+	Page   string `json:"page"`   // HTML file containing the link
+	Link   string `json:"link"`   // Link as written in the href or src attribute
+	Reason string `json:"reason"` // Why the link is broken, e.g. LinkMissing
+}
+
+// checker caches element ids of HTML files under dst
+type checker struct {
+	dst string
+	url string
+	ids map[string]Set
+}
+
+// Check parses all HTML files under dst, and returns broken links
+// found in href and src attributes. Links are resolved against dst,
+// with absolute URLs under url treated as links within dst.
+// Links to other sites are not checked.
+//
+// Anchors are checked against the ids in the linked pages, including
+// heading ids generated by ssg-go. Links to .md files are always reported,
+// since Markdown files are converted to HTML.
+func Check(dst, url string) ([]BrokenLink, error) {
+	c := &checker{
+		dst: filepath.Clean(dst),
+		url: strings.TrimSuffix(url, "/"),
+		ids: make(map[string]Set),
+	}
+
+	var broken []BrokenLink
+	err := filepath.WalkDir(c.dst, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !isHtml(path) {
+			return nil
+		}
+		links, err := c.check(path)
+		if err != nil {
+			return err
+		}
+		broken = append(broken, links...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return broken, nil
+}
+
+// FprintBrokenLinks prints broken links to w, one line per link
+func FprintBrokenLinks(w io.Writer, broken []BrokenLink) {
+	for i := range broken {
+		b := &broken[i]
+		Fprintf(w, "%-14s %s: %s\n", b.Reason, b.Page, b.Link)
+	}
+}
+
+// check returns broken links in HTML file at path
+func (c *checker) check(path string) ([]BrokenLink, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	links, ids, err := parseLinks(data)
+	if err != nil {
+		return nil, err
+	}
+	c.ids[path] = ids
+
+	var broken []BrokenLink
+	for _, link := range links {
+		reason, err := c.resolve(path, link)
+		if err != nil {
+			return nil, err
+		}
+		if reason == "" {
+			continue
+		}
+		broken = append(broken, BrokenLink{
+			Page:   path,
+			Link:   link,
+			Reason: reason,
+		})
+	}
+	return broken, nil
+}
+
+// resolve returns why link in page is broken, or an empty string
+func (c *checker) resolve(page, link string) (string, error) {
+	if c.url != "" && strings.HasPrefix(link, c.url) {
+		rest := strings.TrimPrefix(link, c.url)
+		if rest == "" || rest[0] == '/' || rest[0] == '#' || rest[0] == '?' {
+			link = "/" + strings.TrimPrefix(rest, "/")
+		}
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return LinkMissing, nil
+	}
+	if u.Scheme != "" || u.Host != "" || u.Opaque != "" {
+		return "", nil // Links to other sites, or mailto: and the like
+	}
+
+	target := page
+	if u.Path != "" {
+		p := u.Path
+		if !strings.HasPrefix(p, "/") {
+			rel, err := filepath.Rel(c.dst, filepath.Dir(page))
+			if err != nil {
+				return "", err
+			}
+			p = path.Join("/", filepath.ToSlash(rel), p)
+		}
+		if path.Ext(p) == ".md" {
+			return LinkMarkdown, nil
+		}
+		// path.Join removes trailing slash, and guards against ".." escaping dst
+		target = filepath.Join(c.dst, filepath.FromSlash(path.Join("/", p)))
+		stat, err := os.Stat(target)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return LinkMissing, nil
+			}
+			return "", err
+		}
+		if stat.IsDir() {
+			target = filepath.Join(target, "index.html")
+			_, err := os.Stat(target)
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return LinkMissing, nil
+				}
+				return "", err
+			}
+		}
+	}
+
+	// Empty fragments and #top scroll to the top of pages, see the HTML spec
+	if u.Fragment == "" || strings.EqualFold(u.Fragment, "top") || !isHtml(target) {
+		return "", nil
+	}
+	ids, err := c.idsOf(target)
+	if err != nil {
+		return "", err
+	}
+	if !ids.Contains(u.Fragment) {
+		return LinkMissingAnchor, nil
+	}
+	return "", nil
+}
+
+// idsOf returns element ids of HTML file at path, including names of <a> elements
+func (c *checker) idsOf(path string) (Set, error) {
+	ids, ok := c.ids[path]
+	if ok {
+		return ids, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	_, ids, err = parseLinks(data)
+	if err != nil {
+		return nil, err
+	}
+	c.ids[path] = ids
+	return ids, nil
+}
+
+// parseLinks returns values of href and src attributes,
+// and element ids in HTML document data
+func parseLinks(data []byte) ([]string, Set, error) {
+	var links []string
+	ids := make(Set)
+	z := html.NewTokenizer(bytes.NewReader(data))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if errors.Is(z.Err(), io.EOF) {
+				return links, ids, nil
+			}
+			return nil, nil, z.Err()
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			for hasAttr {
+				var k, v []byte
+				k, v, hasAttr = z.TagAttr()
+				switch string(k) {
+				case "href", "src":
+					link := strings.TrimSpace(string(v))
+					if link != "" {
+						links = append(links, link)
+					}
+				case "id":
+					ids.Insert(string(v))
+				case "name":
+					if string(name) == "a" {
+						ids.Insert(string(v))
+					}
+				}
+			}
+		}
+	}
+}
+
+func isHtml(path string) bool {
+	switch filepath.Ext(path) {
+	case ".html", ".htm":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package ssg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	root := t.TempDir()
+	src, dst := filepath.Join(root, "src"), filepath.Join(root, "dst")
+	files := map[string]string{
+		"index.md": `# Intro
+
+- [About](about.html)
+- [Team](/about.html#team)
+- [Self](#intro)
+- [Top](#top)
+- [Top of about](about.html#TOP)
+- [Empty](#)
+- [Empty of about](about.html#)
+- [Absolute](https://example.com/blog/)
+- [Other site](https://other.example/missing.html)
+- [Mail](mailto:johndoe@example.com)
+- [Missing](missing.html)
+- [Markdown](about.md)
+- [Missing anchor](about.html#nope)
+- [Missing absolute](https://example.com/gone/)
+`,
+		"about.md":      "# About\n\n## Team\n\n![Logo](img/logo.svg)\n",
+		"img/logo.svg":  "<svg></svg>",
+		"blog/index.md": "# Blog\n\n[Home](../index.html) [Escape](../../outside.html) [Up](..#intro)\n",
+	}
+	for path, content := range files {
+		path = filepath.Join(src, path)
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			panic(err)
+		}
+		err = os.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			panic(err)
+		}
+	}
+	err := Generate(src, dst, "TestCheck", "https://example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	broken, err := Check(dst, "https://example.com/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		"missing.html":              LinkMissing,
+		"about.md":                  LinkMarkdown,
+		"about.html#nope":           LinkMissingAnchor,
+		"https://example.com/gone/": LinkMissing,
+		"../../outside.html":        LinkMissing,
+	}
+	if len(broken) != len(expected) {
+		t.Fatalf("unexpected broken links: %+v", broken)
+	}
+	for _, b := range broken {
+		reason, ok := expected[b.Link]
+		if !ok {
+			t.Fatalf("unexpected broken link '%s' in %s (%s)", b.Link, b.Page, b.Reason)
+		}
+		if b.Reason != reason {
+			t.Fatalf("unexpected reason for '%s': expecting '%s', got '%s'", b.Link, reason, b.Reason)
+		}
+	}
+}
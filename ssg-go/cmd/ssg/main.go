@@ -1,25 +1,164 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
+	"io"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"syscall"
 
 	"github.com/soyart/ssg/ssg-go"
 )
 
 func main() {
-	if len(os.Args) < 5 {
-		ssg.Fprint(os.Stdout, "usage: ssg src dst title base_url\n")
+	if len(os.Args) > 1 && os.Args[1] == "ignored" {
+		ignored(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "check" {
+		check(os.Args[2:])
+		return
+	}
+
+	report := flag.String("report", "", "write JSON build report to `file` ('-' for stdout)")
+	dryRun := flag.Bool("dry-run", false, "print changes to dst without writing")
+	diff := flag.Bool("diff", false, "with -dry-run, also print unified diffs of modified HTML outputs")
+	paths := flag.String("paths", string(ssg.PathsUgly), "output path `strategy` of Markdown pages: ugly or pretty")
+	symlinks := flag.String("symlinks", string(ssg.SymlinkSkip), "symlink `policy`: skip, follow or copy")
+	overwrites := flag.Bool("allow-overwrites", false, "allow outputs to overwrite earlier outputs with the same targets")
+	rewriteLinks := flag.Bool("rewrite-links", false, "rewrite relative links to .md sources to their outputs")
+	gzip := flag.Bool("gzip", false, "also write precompressed .gz siblings of compressible outputs")
+	brotli := flag.Bool("brotli", false, "also write precompressed .br siblings of compressible outputs")
+	compressMinSize := flag.Int("compress-min-size", ssg.CompressMinSizeDefault, "minimum `bytes` of outputs to precompress")
+	var dotfiles []string
+	flag.Func("allow-dotfiles", "build dotfiles matching `pattern`, e.g. .well-known (repeatable)", func(s string) error {
+		dotfiles = append(dotfiles, s)
+		return nil
+	})
+	flag.Usage = func() {
+		ssg.Fprint(os.Stdout, "usage: ssg [-report file] [-dry-run [-diff]] [-paths strategy] [-symlinks policy] [-allow-dotfiles pattern]... [-allow-overwrites] [-rewrite-links] [-gzip] [-brotli] [-compress-min-size bytes] src dst title base_url\n")
+		ssg.Fprint(os.Stdout, "       ssg ignored src path...\n")
+		ssg.Fprint(os.Stdout, "       ssg check dst base_url\n")
+	}
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) < 4 {
+		flag.Usage()
 		syscall.Exit(1)
 	}
 
-	src, dst, title, url := os.Args[1], os.Args[2], os.Args[3], os.Args[4]
-	err := ssg.Generate(
-		src, dst, title, url,
+	policy, err := ssg.ParseSymlinkPolicy(*symlinks)
+	if err != nil {
+		panic(err)
+	}
+	strategy, err := ssg.ParsePathStrategy(*paths)
+	if err != nil {
+		panic(err)
+	}
+
+	src, dst, title, url := args[0], args[1], args[2], args[3]
+	opts := []ssg.Option{
 		ssg.WritersFromEnv(),
-	)
+		ssg.WithSymlinks(policy),
+		ssg.WithPathStrategy(strategy),
+		ssg.AllowDotfiles(dotfiles...),
+		ssg.AllowOverwrites(*overwrites),
+		ssg.RewriteLinks(*rewriteLinks),
+		ssg.WithCompression(ssg.Compression{
+			Gzip:    *gzip,
+			Brotli:  *brotli,
+			MinSize: *compressMinSize,
+		}),
+	}
+	stdout := io.Writer(os.Stdout)
+	if *report == "-" {
+		// Keep stdout clean for the report
+		stdout = os.Stderr
+		opts = append(opts, ssg.WithLogger(slog.New(slog.NewTextHandler(os.Stderr, nil))))
+	}
+
+	s := ssg.NewWithOptions(src, dst, title, url, opts...)
+	if *dryRun {
+		var changes []ssg.Change
+		changes, err = s.DryRun(*diff)
+		ssg.FprintChanges(stdout, changes)
+	} else {
+		err = s.Generate()
+	}
 	if err != nil {
-		ssg.Fprintln(os.Stdout, "error with", "src", src, "dst", dst, "title", title, "url", url)
+		ssg.Fprintln(stdout, "error with", "src", src, "dst", dst, "title", title, "url", url)
 		panic(err)
 	}
+
+	if *report == "" {
+		return
+	}
+	err = writeReport(*report, s.Report())
+	if err != nil {
+		panic(err)
+	}
+}
+
+func writeReport(path string, r *ssg.Report) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if path == "-" {
+		_, err = os.Stdout.Write(b)
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+// ignored prints the .ssgignore rule matching each path relative to src,
+// in the format of git check-ignore -v -n: "source:line:pattern<TAB>path",
+// or "::<TAB>path" if no rules match the path.
+func ignored(args []string) {
+	if len(args) < 2 {
+		ssg.Fprint(os.Stdout, "usage: ssg ignored src path...\n")
+		syscall.Exit(1)
+	}
+
+	src := args[0]
+	ignorer := ssg.NewIgnorer(src)
+	for _, path := range args[1:] {
+		full := filepath.Join(src, path)
+		isDir := false
+		if stat, err := os.Lstat(full); err == nil {
+			isDir = stat.IsDir()
+		}
+
+		_, rule, err := ignorer.Match(full, isDir)
+		if err != nil {
+			panic(err)
+		}
+		if rule == nil {
+			ssg.Fprintf(os.Stdout, "::\t%s\n", path)
+			continue
+		}
+		ssg.Fprintf(os.Stdout, "%s\t%s\n", rule, path)
+	}
+}
+
+// check prints broken links in HTML files under dst,
+// and exits with status 1 if there is any
+func check(args []string) {
+	if len(args) != 2 {
+		ssg.Fprint(os.Stdout, "usage: ssg check dst base_url\n")
+		syscall.Exit(1)
+	}
+
+	broken, err := ssg.Check(args[0], args[1])
+	if err != nil {
+		panic(err)
+	}
+	ssg.FprintBrokenLinks(os.Stdout, broken)
+	if len(broken) != 0 {
+		syscall.Exit(1)
+	}
 }
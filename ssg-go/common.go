@@ -2,14 +2,11 @@ package ssg
 
 import (
 	"bytes"
-	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
-	"path/filepath"
 	"strings"
-	"sync"
 
 	"github.com/gomarkdown/markdown"
 	"github.com/gomarkdown/markdown/html"
@@ -108,6 +105,27 @@ func Output(target string, originator string, data []byte, perm fs.FileMode) Out
 	}
 }
 
+// OutputSymlink returns an output that is written as a symlink to link
+func OutputSymlink(target string, originator string, link string) OutputFile {
+	return OutputFile{
+		target:     target,
+		originator: originator,
+		link:       link,
+	}
+}
+
+// OutputRedirect returns an output that is an HTML redirect stub to url.
+// See [RedirectHtml].
+func OutputRedirect(target string, originator string, url string) OutputFile {
+	return OutputFile{
+		target:     target,
+		originator: originator,
+		data:       RedirectHtml(url),
+		perm:       0644,
+		redirect:   url,
+	}
+}
+
 func (o *OutputFile) Target() string {
 	return o.target
 }
@@ -120,6 +138,22 @@ func (o *OutputFile) Data() []byte {
 	return o.data
 }
 
+// Link returns the symlink target if o is a symlink, or an empty string
+func (o *OutputFile) Link() string {
+	return o.link
+}
+
+// Redirect returns the redirect URL if o is a redirect stub, or an empty string
+func (o *OutputFile) Redirect() string {
+	return o.redirect
+}
+
+// Encoding returns the content encoding if o is a precompressed sibling
+// of another output, e.g. "gzip" for index.html.gz, or an empty string
+func (o *OutputFile) Encoding() string {
+	return o.encoding
+}
+
 func (o *OutputFile) Perm() fs.FileMode {
 	if o.perm == fs.FileMode(0) {
 		return fs.ModePerm
@@ -129,61 +163,24 @@ func (o *OutputFile) Perm() fs.FileMode {
 
 // WriteOutSlice blocks and writes concurrently from writes to their output locations.
 func WriteOutSlice(writes []OutputFile, concurrent int) error {
-	if concurrent == 0 {
-		concurrent = 1
-	}
-
-	wg := new(sync.WaitGroup)
-	errs := make(chan errorWrite)
-	guard := make(chan struct{}, concurrent)
-
-	for i := range writes {
-		guard <- struct{}{}
-		wg.Add(1)
-
-		go func(w *OutputFile, wg *sync.WaitGroup) {
-			defer func() {
-				<-guard
-				wg.Done()
-			}()
-
-			err := os.MkdirAll(filepath.Dir(w.target), os.ModePerm)
-			if err != nil {
-				errs <- errorWrite{
-					err:        err,
-					target:     w.target,
-					originator: w.originator,
-				}
-				return
-			}
-			err = os.WriteFile(w.target, w.data, w.Perm())
-			if err != nil {
-				errs <- errorWrite{
-					err:        err,
-					target:     w.target,
-					originator: w.originator,
-				}
-				return
-			}
-
-			Fprintln(os.Stdout, w.target)
-		}(&writes[i], wg)
-	}
+	return writeOutSlice(writes, concurrent, Compression{}, printTarget)
+}
 
+func writeOutSlice(writes []OutputFile, concurrent int, c Compression, onWritten func(*OutputFile)) error {
+	stream := make(chan OutputFile)
 	go func() {
-		wg.Wait()
-		close(errs)
+		defer close(stream)
+		for i := range writes {
+			stream <- writes[i]
+		}
 	}()
 
-	var wErrs []error
-	for err := range errs { // Blocks here until errs is closed
-		wErrs = append(wErrs, err)
-	}
-	if len(wErrs) > 0 {
-		return errors.Join(wErrs...)
-	}
+	_, err := writeOut(stream, concurrent, c, onWritten)
+	return err
+}
 
-	return nil
+func printTarget(o *OutputFile) {
+	Fprintln(os.Stdout, o.target)
 }
 
 func newHeaders(defaultHeader string) headers {
@@ -0,0 +1,138 @@
+package ssg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+const (
+	EncodingGzip   = "gzip"
+	EncodingBrotli = "br"
+
+	CompressMinSizeDefault = 1024
+)
+
+// CompressExtensions are extensions of outputs compressed by [WithCompression]
+var CompressExtensions = []string{
+	".html", ".htm", ".css", ".js", ".mjs", ".json", ".xml", ".svg",
+	".txt", ".md", ".map", ".webmanifest", ".ico", ".ttf", ".otf", ".eot",
+}
+
+// Compression configures precompressed siblings of outputs,
+// e.g. index.html.gz and index.html.br next to index.html,
+// for servers such as nginx with gzip_static.
+//
+// Siblings are compressed and written by the concurrent writers,
+// only for outputs with extensions in [CompressExtensions] and at least MinSize bytes,
+// and only if the compressed data is smaller. Siblings are not listed in sitemap.xml.
+type Compression struct {
+	Gzip    bool `json:"gzip"`     // Write .gz siblings
+	Brotli  bool `json:"brotli"`   // Write .br siblings
+	MinSize int  `json:"min-size"` // Minimum size in bytes of compressed outputs, defaults to CompressMinSizeDefault
+}
+
+func (c Compression) enabled() bool {
+	return c.Gzip || c.Brotli
+}
+
+// compresses returns whether c may write precompressed siblings of o
+func (c Compression) compresses(o *OutputFile) bool {
+	return c.enabled() && o.link == "" && o.encoding == "" && len(o.data) >= c.MinSize && compressible(o.target)
+}
+
+// targets returns targets of precompressed siblings of o, including those
+// not written because o is below MinSize or compression does not help,
+// which are removed if left from earlier builds
+func (c Compression) targets(o *OutputFile) []string {
+	if !c.enabled() || o.link != "" || o.encoding != "" || !compressible(o.target) {
+		return nil
+	}
+	var targets []string
+	if c.Gzip {
+		targets = append(targets, o.target+".gz")
+	}
+	if c.Brotli {
+		targets = append(targets, o.target+".br")
+	}
+	return targets
+}
+
+// siblings returns precompressed siblings of o
+func (c Compression) siblings(o *OutputFile) ([]OutputFile, error) {
+	if !c.compresses(o) {
+		return nil, nil
+	}
+
+	var siblings []OutputFile
+	add := func(encoding string, data []byte) {
+		if len(data) >= len(o.data) {
+			return // Compression does not help
+		}
+		ext := ".gz"
+		if encoding == EncodingBrotli {
+			ext = ".br"
+		}
+		siblings = append(siblings, OutputFile{
+			target:     o.target + ext,
+			originator: o.originator,
+			data:       data,
+			perm:       o.perm,
+			encoding:   encoding,
+		})
+	}
+
+	if c.Gzip {
+		b := bytes.NewBuffer(nil)
+		w, err := gzip.NewWriterLevel(b, gzip.BestCompression)
+		if err != nil {
+			return nil, err
+		}
+		_, err = w.Write(o.data)
+		if err == nil {
+			err = w.Close()
+		}
+		if err != nil {
+			return nil, err
+		}
+		add(EncodingGzip, b.Bytes())
+	}
+	if c.Brotli {
+		b := bytes.NewBuffer(nil)
+		w := brotli.NewWriterLevel(b, brotli.BestCompression)
+		_, err := w.Write(o.data)
+		if err == nil {
+			err = w.Close()
+		}
+		if err != nil {
+			return nil, err
+		}
+		add(EncodingBrotli, b.Bytes())
+	}
+	return siblings, nil
+}
+
+// stale returns targets of precompressed siblings of o not in siblings
+func (c Compression) stale(o *OutputFile, siblings []OutputFile) []string {
+	var stale []string
+	for _, target := range c.targets(o) {
+		if !slices.ContainsFunc(siblings, func(s OutputFile) bool { return s.target == target }) {
+			stale = append(stale, target)
+		}
+	}
+	return stale
+}
+
+func compressible(target string) bool {
+	ext := strings.ToLower(filepath.Ext(target))
+	for i := range CompressExtensions {
+		if ext == CompressExtensions[i] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package ssg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestCompression(t *testing.T) {
+	root := t.TempDir()
+	src, dst := filepath.Join(root, "src"), filepath.Join(root, "dst")
+	random := make([]byte, 2048)
+	_, err := rand.Read(random)
+	if err != nil {
+		panic(err)
+	}
+	files := map[string]string{
+		"index.md":  "# Home\n\n" + strings.Repeat("Some compressible text. ", 100),
+		"small.md":  "# Small",
+		"img.png":   strings.Repeat("png", 1000),
+		"random.js": string(random),
+	}
+	for path, content := range files {
+		path = filepath.Join(src, path)
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			panic(err)
+		}
+		err = os.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	s := NewWithOptions(src, dst, "TestCompression", "https://example.com",
+		WithCompression(Compression{Gzip: true, Brotli: true, MinSize: 512}),
+	)
+	err = s.Generate()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		".gz": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		".br": func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+	}
+	for ext, decode := range decoders {
+		for _, target := range []string{"index.html", "sitemap.xml"} {
+			original, err := os.ReadFile(filepath.Join(dst, target))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			compressed, err := os.ReadFile(filepath.Join(dst, target+ext))
+			if err != nil {
+				t.Fatalf("missing %s%s: %v", target, ext, err)
+			}
+			if len(compressed) >= len(original) {
+				t.Fatalf("unexpected size of %s%s: %d >= %d", target, ext, len(compressed), len(original))
+			}
+			r, err := decode(bytes.NewReader(compressed))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			decompressed, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(decompressed, original) {
+				t.Fatalf("unexpected content of %s%s", target, ext)
+			}
+		}
+
+		// Below min size, not compressible, and not shrinking
+		for _, target := range []string{"small.html", "img.png", "random.js"} {
+			_, err := os.Stat(filepath.Join(dst, target+ext))
+			if !os.IsNotExist(err) {
+				t.Fatalf("unexpected %s%s: %v", target, ext, err)
+			}
+		}
+	}
+
+	sitemap, err := os.ReadFile(filepath.Join(dst, "sitemap.xml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bytes.Contains(sitemap, []byte(".gz<")) || bytes.Contains(sitemap, []byte(".br<")) {
+		t.Fatalf("unexpected precompressed siblings in sitemap:\n%s", sitemap)
+	}
+
+	// Dry runs also expect the siblings
+	changes, err := s.DryRun(false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, c := range changes {
+		if c.Kind != ChangeUnchanged {
+			t.Fatalf("unexpected change %s of %s", c.Kind, c.Target)
+		}
+	}
+
+	// Siblings left from earlier builds are removed on rebuilds
+	// if they are no longer written, e.g. with larger MinSize
+	stale := []string{"small.html.gz", "random.js.br", "index.html.gz", "index.html.br"}
+	for _, target := range stale[:2] {
+		err = os.WriteFile(filepath.Join(dst, target), []byte("stale"), 0644)
+		if err != nil {
+			panic(err)
+		}
+	}
+	s = NewWithOptions(src, dst, "TestCompression", "https://example.com",
+		WithCompression(Compression{Gzip: true, Brotli: true, MinSize: 1 << 20}),
+	)
+	err = s.Generate()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, target := range stale {
+		_, err := os.Stat(filepath.Join(dst, target))
+		if !os.IsNotExist(err) {
+			t.Fatalf("unexpected stale %s: %v", target, err)
+		}
+	}
+
+	// Zero MinSize defaults to CompressMinSizeDefault, like in soyweb manifests
+	s = NewWithOptions(src, dst, "TestCompression", "https://example.com",
+		WithCompression(Compression{Gzip: true}),
+	)
+	if s.options.compression.MinSize != CompressMinSizeDefault {
+		t.Fatalf("unexpected default min size %d", s.options.compression.MinSize)
+	}
+}
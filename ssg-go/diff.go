@@ -0,0 +1,379 @@
+package ssg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type ChangeKind string
+
+const (
+	ChangeAdded     ChangeKind = "added"
+	ChangeModified  ChangeKind = "modified"
+	ChangeUnchanged ChangeKind = "unchanged"
+	ChangeRemoved   ChangeKind = "removed"
+	ChangeUntracked ChangeKind = "untracked"
+
+	diffContext = 3
+)
+
+// Change describes what writing an output would do to the file at its target
+type Change struct {
+	Kind       ChangeKind `json:"kind"`
+	Target     string     `json:"target"`
+	Originator string     `json:"originator,omitempty"`
+	Diff       string     `json:"diff,omitempty"`
+}
+
+// DryRun builds a one-off [Ssg] and compares the outputs with files in dst.
+// See [Ssg.DryRun].
+func DryRun(src, dst, title, url string, diff bool, opts ...Option) ([]Change, error) {
+	return NewWithOptions(src, dst, title, url, opts...).DryRun(diff)
+}
+
+// DryRun builds s and compares the outputs, including metadata,
+// with the files currently in s.Dst without writing anything.
+//
+// Files in s.Dst that would not be written are reported as untracked.
+// Builds never remove them.
+// If diff is true, modified HTML outputs will have unified diffs.
+func (s *Ssg) DryRun(diff bool) ([]Change, error) {
+	stat, err := os.Stat(s.Src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat src '%s': %w", s.Src, err)
+	}
+
+	caching := s.options.caching
+	s.options.caching = true
+	defer func() { s.options.caching = caching }()
+
+	files, outputs, err := build(s, nil)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := Metadata(s.Src, s.Dst, s.Url, files, outputs, stat.ModTime())
+	if err != nil {
+		return nil, err
+	}
+	err = s.addMetadata(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs = s.result.cache
+	for i, n := 0, len(outputs); i < n; i++ {
+		siblings, err := s.options.compression.siblings(&outputs[i])
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, siblings...)
+	}
+
+	return Changes(s.Dst, outputs, diff)
+}
+
+// Changes is like [Compare], but also reports files under dst
+// not targeted by any of the outputs as untracked.
+func Changes(dst string, outputs []OutputFile, diff bool) ([]Change, error) {
+	changes, err := Compare(outputs, diff)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make(Set)
+	for i := range outputs {
+		targets.Insert(outputs[i].target)
+	}
+	untracked, err := walkChanges(dst, targets, ChangeUntracked)
+	if err != nil {
+		return nil, err
+	}
+
+	changes = append(changes, untracked...)
+	sortChanges(changes)
+	return changes, nil
+}
+
+// Removals reports all files under dir as removed, e.g. by cleanups.
+func Removals(dir string) ([]Change, error) {
+	changes, err := walkChanges(dir, nil, ChangeRemoved)
+	if err != nil {
+		return nil, err
+	}
+	sortChanges(changes)
+	return changes, nil
+}
+
+// walkChanges returns changes of kind for files under dir not in targets
+func walkChanges(dir string, targets Set, kind ChangeKind) ([]Change, error) {
+	var changes []Change
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || targets.Contains(path) {
+			return nil
+		}
+		changes = append(changes, Change{Kind: kind, Target: path})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// Compare compares each output with the existing file at its target.
+// If diff is true, modified HTML outputs will have unified diffs.
+func Compare(outputs []OutputFile, diff bool) ([]Change, error) {
+	changes := make([]Change, len(outputs))
+	for i := range outputs {
+		o := &outputs[i]
+		c := Change{Target: o.target, Originator: o.originator}
+		if o.link != "" {
+			c.Kind = compareLink(o)
+			changes[i] = c
+			continue
+		}
+
+		old, err := os.ReadFile(o.target)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			c.Kind = ChangeAdded
+
+		case err != nil:
+			return nil, fmt.Errorf("failed to read existing target '%s': %w", o.target, err)
+
+		case bytes.Equal(old, o.data):
+			c.Kind = ChangeUnchanged
+
+		default:
+			c.Kind = ChangeModified
+			if diff && filepath.Ext(o.target) == ".html" {
+				c.Diff = UnifiedDiff(o.target, o.target, old, o.data)
+			}
+		}
+
+		changes[i] = c
+	}
+
+	sortChanges(changes)
+	return changes, nil
+}
+
+// compareLink compares symlink output o with the existing file at its target
+func compareLink(o *OutputFile) ChangeKind {
+	link, err := os.Readlink(o.target)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return ChangeAdded
+	case err == nil && link == o.link:
+		return ChangeUnchanged
+	}
+	return ChangeModified
+}
+
+// FprintChanges prints changes to w, one line per change
+// followed by its diff, if any.
+func FprintChanges(w io.Writer, changes []Change) {
+	for i := range changes {
+		c := &changes[i]
+		Fprintf(w, "%-10s %s\n", c.Kind, c.Target)
+		if c.Diff != "" {
+			Fprint(w, c.Diff)
+		}
+	}
+}
+
+// UnifiedDiff returns unified diff of lines from old to new,
+// or an empty string if there's no difference.
+func UnifiedDiff(nameOld, nameNew string, old, new []byte) string {
+	a, b := splitLines(old), splitLines(new)
+	ops := diffLines(a, b)
+
+	// Find ranges of ops to print, with context lines around the edits
+	type hunk struct{ start, end int }
+	var hunks []hunk
+	for i := range ops {
+		if ops[i].kind == ' ' {
+			continue
+		}
+		start, end := max(i-diffContext, 0), min(i+diffContext+1, len(ops))
+		if l := len(hunks); l > 0 && start <= hunks[l-1].end {
+			hunks[l-1].end = end
+			continue
+		}
+		hunks = append(hunks, hunk{start: start, end: end})
+	}
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	out := bytes.NewBuffer(nil)
+	Fprintf(out, "--- %s\n+++ %s\n", nameOld, nameNew)
+	for _, h := range hunks {
+		lineA, lineB := ops[h.start].a+1, ops[h.start].b+1
+		countA, countB := 0, 0
+		for _, op := range ops[h.start:h.end] {
+			if op.kind != '+' {
+				countA++
+			}
+			if op.kind != '-' {
+				countB++
+			}
+		}
+		if countA == 0 {
+			lineA--
+		}
+		if countB == 0 {
+			lineB--
+		}
+		Fprintf(out, "@@ -%d,%d +%d,%d @@\n", lineA, countA, lineB, countB)
+		for _, op := range ops[h.start:h.end] {
+			line := op.line
+			if !strings.HasSuffix(line, "\n") {
+				line += "\n\\ No newline at end of file\n"
+			}
+			Fprintf(out, "%c%s", op.kind, line)
+		}
+	}
+
+	return out.String()
+}
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+	a, b int // Line indices in old and new
+}
+
+// diffLines computes the shortest edit script from a to b
+// with the linear space variant of Myers' algorithm.
+func diffLines(a, b []string) []diffOp {
+	d := &differ{a: a, b: b}
+	d.diff(0, len(a), 0, len(b))
+	return d.ops
+}
+
+// differ collects ops of diffLines in order
+type differ struct {
+	a, b []string
+	ops  []diffOp
+}
+
+// diff appends ops from a[a0:a1] to b[b0:b1]
+func (d *differ) diff(a0, a1, b0, b1 int) {
+	for a0 < a1 && b0 < b1 && d.a[a0] == d.b[b0] {
+		d.ops = append(d.ops, diffOp{kind: ' ', line: d.a[a0], a: a0, b: b0})
+		a0, b0 = a0+1, b0+1
+	}
+	suffix := 0
+	for a0 < a1-suffix && b0 < b1-suffix && d.a[a1-suffix-1] == d.b[b1-suffix-1] {
+		suffix++
+	}
+	a1, b1 = a1-suffix, b1-suffix
+
+	switch {
+	case a0 == a1:
+		for y := b0; y < b1; y++ {
+			d.ops = append(d.ops, diffOp{kind: '+', line: d.b[y], a: a0, b: y})
+		}
+	case b0 == b1:
+		for x := a0; x < a1; x++ {
+			d.ops = append(d.ops, diffOp{kind: '-', line: d.a[x], a: x, b: b0})
+		}
+	default:
+		x, y, u, v := d.middleSnake(a0, a1, b0, b1)
+		d.diff(a0, x, b0, y)
+		for ; x < u; x, y = x+1, y+1 {
+			d.ops = append(d.ops, diffOp{kind: ' ', line: d.a[x], a: x, b: y})
+		}
+		d.diff(u, a1, v, b1)
+	}
+
+	for i := 0; i < suffix; i++ {
+		d.ops = append(d.ops, diffOp{kind: ' ', line: d.a[a1+i], a: a1 + i, b: b1 + i})
+	}
+}
+
+// middleSnake returns the snake from (x, y) to (u, v) in the middle of
+// a shortest edit script from a[a0:a1] to b[b0:b1], by searching forward
+// from the start and backward from the end until the searches overlap.
+func (d *differ) middleSnake(a0, a1, b0, b1 int) (x, y, u, v int) {
+	n, m := a1-a0, b1-b0
+	delta := n - m
+	odd := delta%2 != 0
+	limit := (n + m + 1) / 2
+	offset := limit + 1
+	// Furthest x on diagonals k = x-y searching forward,
+	// and furthest steps back from the end searching backward
+	vf := make([]int, 2*offset+1)
+	vb := make([]int, 2*offset+1)
+
+	for e := 0; e <= limit; e++ {
+		for k := -e; k <= e; k += 2 {
+			var x int
+			if k == -e || (k != e && vf[offset+k-1] < vf[offset+k+1]) {
+				x = vf[offset+k+1]
+			} else {
+				x = vf[offset+k-1] + 1
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && d.a[a0+x] == d.b[b0+y] {
+				x, y = x+1, y+1
+			}
+			vf[offset+k] = x
+			// Backward diagonal with the same x-y is delta-k
+			if r := delta - k; odd && r >= -(e-1) && r <= e-1 && x+vb[offset+r] >= n {
+				return a0 + startX, b0 + startY, a0 + x, b0 + y
+			}
+		}
+		for r := -e; r <= e; r += 2 {
+			var x int
+			if r == -e || (r != e && vb[offset+r-1] < vb[offset+r+1]) {
+				x = vb[offset+r+1]
+			} else {
+				x = vb[offset+r-1] + 1
+			}
+			y := x - r
+			startX, startY := x, y
+			for x < n && y < m && d.a[a1-x-1] == d.b[b1-y-1] {
+				x, y = x+1, y+1
+			}
+			vb[offset+r] = x
+			if k := delta - r; !odd && k >= -e && k <= e && x+vf[offset+k] >= n {
+				return a1 - x, b1 - y, a1 - startX, b1 - startY
+			}
+		}
+	}
+
+	panic("unreachable")
+}
+
+func splitLines(b []byte) []string {
+	if len(b) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(b), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func sortChanges(changes []Change) {
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Target < changes[j].Target
+	})
+}
@@ -0,0 +1,215 @@
+package ssg
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	type testCase struct {
+		old      string
+		new      string
+		expected string
+	}
+
+	tests := []testCase{
+		{
+			old:      "a\nb\nc\n",
+			new:      "a\nb\nc\n",
+			expected: "",
+		},
+		{
+			old: "a\nb\nc\n",
+			new: "a\nB\nc\n",
+			expected: `--- old
++++ new
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+`,
+		},
+		{
+			old: "",
+			new: "a\n",
+			expected: `--- old
++++ new
+@@ -0,0 +1,1 @@
++a
+`,
+		},
+		{
+			old: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			new: "0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			expected: `--- old
++++ new
+@@ -1,3 +1,4 @@
++0
+ 1
+ 2
+ 3
+@@ -7,4 +8,3 @@
+ 7
+ 8
+ 9
+-10
+`,
+		},
+		{
+			old: "a\nb",
+			new: "a\nc",
+			expected: `--- old
++++ new
+@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++c
+\ No newline at end of file
+`,
+		},
+	}
+
+	for i := range tests {
+		tc := &tests[i]
+		actual := UnifiedDiff("old", "new", []byte(tc.old), []byte(tc.new))
+		if actual != tc.expected {
+			t.Logf("expected:\n%s", tc.expected)
+			t.Logf("actual:\n%s", actual)
+			t.Fatalf("unexpected diff from case %d", i+1)
+		}
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	// Minimal number of edits from a to b, from longest common subsequences
+	edits := func(a, b []string) int {
+		lcs := make([][]int, len(a)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(b)+1)
+		}
+		for i := len(a) - 1; i >= 0; i-- {
+			for j := len(b) - 1; j >= 0; j-- {
+				if a[i] == b[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+		return len(a) + len(b) - 2*lcs[0][0]
+	}
+	lines := func(r *rand.Rand) []string {
+		l := make([]string, r.Intn(30))
+		for i := range l {
+			l[i] = string(rune('a' + r.Intn(4)))
+		}
+		return l
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		a, b := lines(r), lines(r)
+		var actualA, actualB []string
+		actualEdits := 0
+		for _, op := range diffLines(a, b) {
+			if op.kind != '+' {
+				if a[op.a] != op.line {
+					t.Fatalf("[%d] unexpected line '%s' at %d of old", i, op.line, op.a)
+				}
+				actualA = append(actualA, op.line)
+			}
+			if op.kind != '-' {
+				if b[op.b] != op.line {
+					t.Fatalf("[%d] unexpected line '%s' at %d of new", i, op.line, op.b)
+				}
+				actualB = append(actualB, op.line)
+			}
+			if op.kind != ' ' {
+				actualEdits++
+			}
+		}
+		if strings.Join(actualA, "") != strings.Join(a, "") || strings.Join(actualB, "") != strings.Join(b, "") {
+			t.Fatalf("[%d] unexpected ops from %v to %v", i, a, b)
+		}
+		if expected := edits(a, b); actualEdits != expected {
+			t.Fatalf("[%d] unexpected number of edits from %v to %v: expecting %d, got %d", i, a, b, expected, actualEdits)
+		}
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	root := "../testdata/johndoe.com"
+	src := filepath.Join(root, "/src")
+	dst := filepath.Join(root, "/dstDryRun")
+	title := "JohnDoe.com"
+	url := "https://johndoe.com"
+
+	err := os.RemoveAll(dst)
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dst)
+
+	changes, err := DryRun(src, dst, title, url, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changes) == 0 {
+		t.Fatal("unexpected empty changes")
+	}
+	for i := range changes {
+		if changes[i].Kind != ChangeAdded {
+			t.Fatalf("unexpected change kind for %s: %s", changes[i].Target, changes[i].Kind)
+		}
+	}
+	_, err = os.Stat(dst)
+	if !os.IsNotExist(err) {
+		t.Fatalf("unexpected dst written by dry-run: %v", err)
+	}
+
+	err = Generate(src, dst, title, url)
+	if err != nil {
+		panic(err)
+	}
+
+	modified := filepath.Join(dst, "index.html")
+	untracked := filepath.Join(dst, "stale.html")
+	err = os.WriteFile(modified, []byte("old content\n"), 0644)
+	if err != nil {
+		panic(err)
+	}
+	err = os.WriteFile(untracked, []byte("stale"), 0644)
+	if err != nil {
+		panic(err)
+	}
+
+	changes, err = DryRun(src, dst, title, url, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := range changes {
+		c := &changes[i]
+		switch c.Target {
+		case modified:
+			if c.Kind != ChangeModified {
+				t.Fatalf("unexpected change kind for %s: %s", c.Target, c.Kind)
+			}
+			if c.Diff == "" {
+				t.Fatalf("missing diff for %s", c.Target)
+			}
+		case untracked:
+			if c.Kind != ChangeUntracked {
+				t.Fatalf("unexpected change kind for %s: %s", c.Target, c.Kind)
+			}
+		default:
+			if c.Kind != ChangeUnchanged {
+				t.Fatalf("unexpected change kind for %s: %s", c.Target, c.Kind)
+			}
+		}
+	}
+}
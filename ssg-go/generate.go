@@ -6,10 +6,12 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 )
 
 func generate(s *Ssg) error {
 	const bufferMultiplier = 2
+	start := time.Now()
 	stat, err := os.Stat(s.Src)
 	if err != nil {
 		return fmt.Errorf("failed to stat src '%s': %w", s.Src, err)
@@ -17,6 +19,7 @@ func generate(s *Ssg) error {
 
 	stream := make(chan OutputFile, s.options.writers*bufferMultiplier)
 	outputs := NewOutputsStreaming(stream)
+	report := newReport(s)
 
 	var wg sync.WaitGroup
 	wg.Add(2)
@@ -29,7 +32,7 @@ func generate(s *Ssg) error {
 		}()
 
 		var err error
-		files, _, err = s.Build(outputs)
+		files, _, err = buildReport(s, outputs, report)
 		if err != nil {
 			errBuild = err
 		}
@@ -41,7 +44,12 @@ func generate(s *Ssg) error {
 		defer wg.Done()
 		var err error
 
-		written, err = WriteOut(stream, s.options.writers)
+		startWrite := time.Now()
+		written, err = writeOut(stream, s.options.writers, s.options.compression, func(o *OutputFile) {
+			report.output(o)
+			s.written(o)
+		})
+		report.Timings.Write = time.Since(startWrite)
 		if err != nil {
 			errWrites = err
 		}
@@ -58,10 +66,26 @@ func generate(s *Ssg) error {
 	if errWrites != nil {
 		return fmt.Errorf("streaming_write_error: %w", errWrites)
 	}
-	err = GenerateMetadata(s.Src, s.Dst, s.Url, files, written, stat.ModTime())
+
+	startMetadata := time.Now()
+	metadata, err := Metadata(s.Src, s.Dst, s.Url, files, written, stat.ModTime())
+	if err != nil {
+		return err
+	}
+	err = s.addMetadata(metadata)
 	if err != nil {
 		return err
 	}
+	err = writeOutSlice(metadata, 2, s.options.compression, func(o *OutputFile) {
+		report.output(o)
+		s.written(o)
+	})
+	if err != nil {
+		return err
+	}
+
+	report.Timings.Metadata = time.Since(startMetadata)
+	report.Timings.Total = time.Since(start)
 	s.pront(len(written) + 2)
 	return nil
 }
@@ -69,6 +93,12 @@ func generate(s *Ssg) error {
 // WriteOut blocks and concurrently writes outputs from stream until stream is closed.
 // It returns metadata for all outputs written, without the data.
 func WriteOut(stream <-chan OutputFile, concurrent int) ([]OutputFile, error) {
+	return writeOut(stream, concurrent, Compression{}, printTarget)
+}
+
+// writeOut is like WriteOut, but also writes precompressed siblings with c,
+// and calls onWritten concurrently for each output written.
+func writeOut(stream <-chan OutputFile, concurrent int, c Compression, onWritten func(*OutputFile)) ([]OutputFile, error) {
 	if concurrent == 0 {
 		concurrent = 1
 	}
@@ -98,21 +128,55 @@ func WriteOut(stream <-chan OutputFile, concurrent int) ([]OutputFile, error) {
 				}
 				return
 			}
-			err = os.WriteFile(w.target, w.data, w.Perm())
+			siblings, err := c.siblings(w)
 			if err != nil {
 				errs <- errorWrite{
-					err:        err,
+					err:        fmt.Errorf("failed to compress: %w", err),
 					target:     w.target,
 					originator: w.originator,
 				}
 				return
 			}
+			outputs := append([]OutputFile{*w}, siblings...)
+			for i := range outputs {
+				err = outputs[i].write()
+				if err != nil {
+					errs <- errorWrite{
+						err:        err,
+						target:     outputs[i].target,
+						originator: outputs[i].originator,
+					}
+					return
+				}
+			}
+			// Siblings from earlier builds would otherwise be served instead of w
+			for _, stale := range c.stale(w, siblings) {
+				err = os.Remove(stale)
+				if err != nil && !os.IsNotExist(err) {
+					errs <- errorWrite{
+						err:        fmt.Errorf("failed to remove stale precompressed sibling: %w", err),
+						target:     stale,
+						originator: w.originator,
+					}
+					return
+				}
+			}
 
 			mut.Lock()
 			defer mut.Unlock()
 
-			written = append(written, Output(w.target, w.originator, nil, w.perm))
-			Fprintln(os.Stdout, w.target)
+			for i := range outputs {
+				o := &outputs[i]
+				written = append(written, OutputFile{
+					target:     o.target,
+					originator: o.originator,
+					perm:       o.perm,
+					link:       o.link,
+					redirect:   o.redirect,
+					encoding:   o.encoding,
+				})
+				onWritten(o)
+			}
 		}(&w, wg)
 	}
 
@@ -2,9 +2,12 @@ package ssg
 
 import (
 	"bytes"
+	"encoding/json"
 	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -42,6 +45,85 @@ func TestGenerateStreaming(t *testing.T) {
 	testCmpDeepEqual(t, &caching, &streaming)
 }
 
+func TestGenerateReport(t *testing.T) {
+	root := "../testdata/johndoe.com"
+	src := filepath.Join(root, "/src")
+	dst := filepath.Join(root, "/dstReport")
+
+	err := os.RemoveAll(dst)
+	if err != nil {
+		panic(err)
+	}
+
+	logs := bytes.NewBuffer(nil)
+	s := NewWithOptions(src, dst, "JohnDoe.com", "https://johndoe.com",
+		WithLogger(slog.New(slog.NewJSONHandler(logs, nil))),
+		WithHooks(func(_ string, data []byte) ([]byte, error) { return data, nil }),
+	)
+	err = s.Generate()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r := s.Report()
+	if r == nil {
+		t.Fatal("unexpected nil report")
+	}
+	if len(r.Inputs) == 0 {
+		t.Fatal("unexpected empty inputs")
+	}
+	if len(r.Timings.Hooks) != 1 {
+		t.Fatalf("unexpected len of hook timings %d", len(r.Timings.Hooks))
+	}
+
+	// Every output including metadata must be logged and reported with its size
+	targets := make(Set)
+	for _, o := range r.Outputs {
+		stat, err := os.Stat(o.Target)
+		if err != nil {
+			t.Fatalf("unexpected error from stat '%s': %v", o.Target, err)
+		}
+		if int(stat.Size()) != o.Size {
+			t.Fatalf("unexpected size for '%s': expected=%d, actual=%d", o.Target, stat.Size(), o.Size)
+		}
+		targets.Insert(o.Target)
+	}
+	if !targets.Contains(filepath.Join(dst, "sitemap.xml"), filepath.Join(dst, ".files")) {
+		t.Fatal("missing metadata outputs from report")
+	}
+	if n := bytes.Count(logs.Bytes(), []byte(`"msg":"[ssg-go] wrote"`)); n != len(r.Outputs) {
+		t.Fatalf("unexpected number of logged outputs: expected=%d, actual=%d", len(r.Outputs), n)
+	}
+
+	skipped := make(map[string]string)
+	for _, skip := range r.Skipped {
+		skipped[skip.Path] = skip.Reason
+	}
+	expectedSkips := map[string]string{
+		"/_header.html":          SkipReasonMarker,
+		"/.ssgignore":            SkipReasonDot,
+		"/blog/.hidden":          SkipReasonDot,
+		"/blog/2023/.secret":     SkipReasonDot,
+		"/testignore/ignored.md": SkipReasonSsgIgnore,
+	}
+	for path, reason := range expectedSkips {
+		path = filepath.Join(src, path)
+		if skipped[path] != reason {
+			t.Fatalf("unexpected skip reason for '%s': expected='%s', actual='%s'", path, reason, skipped[path])
+		}
+	}
+
+	_, err = json.Marshal(r)
+	if err != nil {
+		t.Fatalf("unexpected error from marshaling report: %v", err)
+	}
+
+	err = os.RemoveAll(dst)
+	if err != nil {
+		panic(err)
+	}
+}
+
 func testCmpDeepEqual(t *testing.T, s1, s2 *Ssg) {
 	err := os.RemoveAll(s1.Dst)
 	if err != nil {
@@ -149,3 +231,103 @@ func deepEqual(t *testing.T, dst1, dst2 string) fs.WalkDirFunc {
 		return nil
 	}
 }
+
+// TestGenerateTargets tests that all targets of a build are checked for duplicates,
+// and that only the last output for each target is written with AllowOverwrites
+func TestGenerateTargets(t *testing.T) {
+	css := strings.Repeat("body { color: red; }\n", 100)
+	write := func(files map[string]string) string {
+		src := filepath.Join(t.TempDir(), "src")
+		for path, content := range files {
+			path = filepath.Join(src, path)
+			err := os.MkdirAll(filepath.Dir(path), 0755)
+			if err != nil {
+				panic(err)
+			}
+			err = os.WriteFile(path, []byte(content), 0644)
+			if err != nil {
+				panic(err)
+			}
+		}
+		return src
+	}
+	// Renames b.txt to a.txt, colliding with output of a.txt
+	rename := func(path string, data []byte, d fs.DirEntry) (string, []byte, fs.DirEntry, error) {
+		if filepath.Base(path) == "b.txt" {
+			return filepath.Join(filepath.Dir(path), "a.txt"), data, d, nil
+		}
+		return path, data, d, nil
+	}
+	renameOutputs := func(outputs []OutputFile) ([]OutputFile, error) {
+		for i := range outputs {
+			if filepath.Base(outputs[i].target) == "b.txt" {
+				outputs[i].target = filepath.Join(filepath.Dir(outputs[i].target), "a.txt")
+			}
+		}
+		return outputs, nil
+	}
+
+	type testCase struct {
+		files    map[string]string
+		opts     []Option
+		expected string // Expected duplicate target
+	}
+
+	tests := []testCase{
+		{
+			files:    map[string]string{"index.md": "# Index", "sitemap.xml": "<urlset></urlset>"},
+			expected: "sitemap.xml",
+		},
+		{
+			files:    map[string]string{"style.css": css, "style.css.gz": "gzip"},
+			opts:     []Option{WithCompression(Compression{Gzip: true, MinSize: 512})},
+			expected: "style.css.gz",
+		},
+		{
+			files:    map[string]string{"a.txt": "a", "b.txt": "b"},
+			opts:     []Option{WithHooksOutputs(renameOutputs)},
+			expected: "a.txt",
+		},
+	}
+
+	for i := range tests {
+		tc := &tests[i]
+		src := write(tc.files)
+		err := NewWithOptions(src, filepath.Join(filepath.Dir(src), "dst"), "TestGenerateTargets", "https://example.com", tc.opts...).Generate()
+		if err == nil {
+			t.Fatalf("[%d] expecting error from duplicate target %s", i, tc.expected)
+		}
+		if !strings.Contains(err.Error(), "duplicate target") || !strings.Contains(err.Error(), tc.expected) {
+			t.Fatalf("[%d] unexpected error: %v", i, err)
+		}
+	}
+
+	src := write(map[string]string{"index.md": "# Index", "a.txt": "a", "b.txt": "b", "sitemap.xml": "<urlset></urlset>"})
+	dst := filepath.Join(filepath.Dir(src), "dst")
+	s := NewWithOptions(src, dst, "TestGenerateTargets", "https://example.com",
+		Writers(20),
+		WithPipelines(rename),
+		AllowOverwrites(true),
+	)
+	err := s.Generate()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	written := make(map[string]int)
+	for _, o := range s.Report().Outputs {
+		written[o.Target]++
+	}
+	for _, target := range []string{"a.txt", "sitemap.xml"} {
+		if n := written[filepath.Join(dst, target)]; n != 1 {
+			t.Fatalf("unexpected number of writes to %s: expecting 1, got %d", target, n)
+		}
+	}
+	b, err := os.ReadFile(filepath.Join(dst, "a.txt"))
+	if err != nil || string(b) != "b" {
+		t.Fatalf("unexpected overwritten a.txt: %s, %v", b, err)
+	}
+	b, err = os.ReadFile(filepath.Join(dst, "sitemap.xml"))
+	if err != nil || !strings.Contains(string(b), "<loc>https://example.com/</loc>") {
+		t.Fatalf("unexpected overwritten sitemap.xml: %s, %v", b, err)
+	}
+}
@@ -3,12 +3,7 @@ module github.com/soyart/ssg/ssg-go
 go 1.22.7
 
 require (
+	github.com/andybalholm/brotli v1.2.0
 	github.com/gomarkdown/markdown v0.0.0-20250311123330-531bef5e742b
-	github.com/sabhiram/go-gitignore v0.0.0-20210923224102-525f6e181f06
-)
-
-require (
-	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/stretchr/testify v1.7.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	golang.org/x/net v0.35.0
 )
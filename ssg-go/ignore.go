@@ -0,0 +1,304 @@
+package ssg
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Ignorer matches paths under root against .ssgignore files,
+// following .gitignore semantics, except that "!" rules can re-include
+// paths under ignored directories. Files are loaded lazily and cached.
+type Ignorer struct {
+	root  string
+	mut   sync.Mutex
+	rules map[string][]IgnoreRule // Rules by directory, nil if no .ssgignore
+}
+
+// IgnoreRule is a rule from a line in an .ssgignore file
+type IgnoreRule struct {
+	Source  string // Path to the .ssgignore file
+	Line    int    // Line number in Source
+	Pattern string // Pattern as written in Source
+	Negate  bool   // Rule re-includes matching paths
+
+	dirOnly bool
+	re      *regexp.Regexp
+}
+
+// NewIgnorer returns an [Ignorer] for .ssgignore files under root
+func NewIgnorer(root string) *Ignorer {
+	return &Ignorer{
+		root:  filepath.Clean(root),
+		rules: make(map[string][]IgnoreRule),
+	}
+}
+
+// ParseSsgIgnore parses .ssgignore at path, and returns an [Ignorer]
+// rooted at the directory of path, or nil if path does not exist.
+func ParseSsgIgnore(path string) (*Ignorer, error) {
+	_, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to parse ssgignore at %s: %w", path, err)
+	}
+
+	i := NewIgnorer(filepath.Dir(path))
+	_, err = i.load(i.root)
+	if err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+func (r *IgnoreRule) String() string {
+	return fmt.Sprintf("%s:%d:%s", r.Source, r.Line, r.Pattern)
+}
+
+// Ignore returns whether path is ignored,
+// treating path as directory if it is one on the filesystem.
+func (i *Ignorer) Ignore(path string) bool {
+	if i == nil {
+		return false
+	}
+	isDir := false
+	if stat, err := os.Lstat(path); err == nil {
+		isDir = stat.IsDir()
+	}
+	ignored, _, err := i.Match(path, isDir)
+	if err != nil {
+		return false
+	}
+	return ignored
+}
+
+// Match returns whether path is ignored, and the rule that decided it.
+// The rule is nil if no rules match path, and the rule is a negation
+// rule if path was re-included.
+func (i *Ignorer) Match(path string, isDir bool) (bool, *IgnoreRule, error) {
+	if i == nil {
+		return false, nil, nil
+	}
+	path = filepath.Clean(path)
+	rel, err := filepath.Rel(i.root, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false, nil, nil
+	}
+
+	rule, err := i.decide(path, isDir)
+	if err != nil {
+		return false, nil, err
+	}
+	return rule != nil && !rule.Negate, rule, nil
+}
+
+// decide returns the last rule matching path or its parent directories
+func (i *Ignorer) decide(path string, isDir bool) (*IgnoreRule, error) {
+	var decided *IgnoreRule
+	for _, dir := range i.dirs(path) {
+		rules, err := i.load(dir)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return nil, err
+		}
+		rel = filepath.ToSlash(rel)
+		for j := range rules {
+			if rules[j].matches(rel, isDir) {
+				decided = &rules[j]
+			}
+		}
+	}
+	return decided, nil
+}
+
+// matches reports whether r matches slash-separated path rel
+// or any of its parent directories
+func (r *IgnoreRule) matches(rel string, isDir bool) bool {
+	if (isDir || !r.dirOnly) && r.re.MatchString(rel) {
+		return true
+	}
+	for {
+		slash := strings.LastIndexByte(rel, '/')
+		if slash == -1 {
+			return false
+		}
+		rel = rel[:slash]
+		if r.re.MatchString(rel) {
+			return true
+		}
+	}
+}
+
+// dirs returns directories from root to the parent of path, in order
+func (i *Ignorer) dirs(path string) []string {
+	dirs := []string{i.root}
+	rel, err := filepath.Rel(i.root, filepath.Dir(path))
+	if err != nil || rel == "." {
+		return dirs
+	}
+	dir := i.root
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		dir = filepath.Join(dir, part)
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// load reads and caches rules from .ssgignore in dir
+func (i *Ignorer) load(dir string) ([]IgnoreRule, error) {
+	i.mut.Lock()
+	defer i.mut.Unlock()
+
+	rules, ok := i.rules[dir]
+	if ok {
+		return rules, nil
+	}
+
+	source := filepath.Join(dir, SsgIgnore)
+	data, err := os.ReadFile(source)
+	if err != nil {
+		if os.IsNotExist(err) {
+			i.rules[dir] = nil
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to parse ssgignore at %s: %w", source, err)
+	}
+
+	rules, err = parseIgnoreRules(source, data)
+	if err != nil {
+		return nil, err
+	}
+	i.rules[dir] = rules
+	return rules, nil
+}
+
+func parseIgnoreRules(source string, data []byte) ([]IgnoreRule, error) {
+	var rules []IgnoreRule
+	s := bufio.NewScanner(bytes.NewBuffer(data))
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSuffix(s.Text(), "\r")
+		rule, ok, err := parseIgnoreRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("bad pattern at %s:%d: %w", source, n, err)
+		}
+		if !ok {
+			continue
+		}
+		rule.Source, rule.Line = source, n
+		rules = append(rules, rule)
+	}
+	return rules, s.Err()
+}
+
+// parseIgnoreRule parses a .gitignore-style line.
+// It returns false if the line is blank or a comment.
+func parseIgnoreRule(line string) (IgnoreRule, bool, error) {
+	rule := IgnoreRule{Pattern: line}
+
+	// Trailing spaces are ignored unless escaped
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return IgnoreRule{}, false, nil
+	}
+
+	switch {
+	case strings.HasPrefix(line, "!"):
+		rule.Negate = true
+		line = line[1:]
+	case strings.HasPrefix(line, `\!`), strings.HasPrefix(line, `\#`):
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return IgnoreRule{}, false, nil
+	}
+
+	// Patterns with a slash at the start or middle are relative to the .ssgignore,
+	// otherwise they may match at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	expr, err := globToRegexp(line)
+	if err != nil {
+		return IgnoreRule{}, false, err
+	}
+	if !anchored {
+		expr = "(.*/)?" + expr
+	}
+
+	rule.re, err = regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return IgnoreRule{}, false, err
+	}
+	return rule, true, nil
+}
+
+// globToRegexp converts .gitignore glob pattern to regular expression
+func globToRegexp(glob string) (string, error) {
+	expr := new(strings.Builder)
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if !strings.HasPrefix(glob[i:], "**") {
+				expr.WriteString("[^/]*")
+				continue
+			}
+			atStart := i == 0 || glob[i-1] == '/'
+			atEnd := i+2 == len(glob)
+			switch {
+			case atStart && atEnd: // "**" or "foo/**"
+				expr.WriteString(".*")
+				i++
+			case atStart && glob[i+2] == '/': // "**/foo" or "foo/**/bar"
+				expr.WriteString("(.*/)?")
+				i += 2
+			default: // Other consecutive asterisks are regular asterisks
+				expr.WriteString("[^/]*")
+				i++
+			}
+
+		case '?':
+			expr.WriteString("[^/]")
+
+		case '\\':
+			if i+1 == len(glob) {
+				return "", fmt.Errorf("trailing backslash in '%s'", glob)
+			}
+			i++
+			expr.WriteString(regexp.QuoteMeta(string(glob[i])))
+
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end == -1 {
+				return "", fmt.Errorf("unclosed bracket in '%s'", glob)
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + class + "]")
+			i += end + 1
+
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return expr.String(), nil
+}
@@ -0,0 +1,110 @@
+package ssg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSsgignoreNested(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		".ssgignore":           "*.txt\ndrafts/\n!drafts/keep.md\n",
+		"blog/.ssgignore":      "!*.txt\n/secret.md\n",
+		"blog/deep/.ssgignore": "*.md\n!keep.md\n",
+		"index.md":             "# Index",
+		"note.txt":             "note",
+		"drafts/draft.md":      "# Draft",
+		"drafts/keep.md":       "# Keep",
+		"blog/note.txt":        "note",
+		"blog/secret.md":       "# Secret",
+		"blog/deep/secret.md":  "# Not the secret",
+		"blog/deep/post.md":    "# Post",
+		"blog/deep/keep.md":    "# Keep",
+		"blog/deep/note.txt":   "note",
+	}
+	for path, content := range files {
+		path = filepath.Join(root, path)
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			panic(err)
+		}
+		err = os.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	type testCase struct {
+		path     string
+		isDir    bool
+		expected bool
+		source   string // Expected source of the deciding rule, relative to root
+	}
+
+	tests := []testCase{
+		{path: "index.md", expected: false},
+		{path: "note.txt", expected: true, source: ".ssgignore"},
+		{path: "drafts", isDir: true, expected: true, source: ".ssgignore"},
+		{path: "drafts/draft.md", expected: true, source: ".ssgignore"},
+		{path: "drafts/keep.md", expected: false, source: ".ssgignore"},
+		{path: "blog/note.txt", expected: false, source: "blog/.ssgignore"},
+		{path: "blog/secret.md", expected: true, source: "blog/.ssgignore"},
+		{path: "blog/deep/secret.md", expected: true, source: "blog/deep/.ssgignore"},
+		{path: "blog/deep/post.md", expected: true, source: "blog/deep/.ssgignore"},
+		{path: "blog/deep/keep.md", expected: false, source: "blog/deep/.ssgignore"},
+		{path: "blog/deep/note.txt", expected: false, source: "blog/.ssgignore"},
+	}
+
+	ignorer := NewIgnorer(root)
+	for i := range tests {
+		tc := &tests[i]
+		ignored, rule, err := ignorer.Match(filepath.Join(root, tc.path), tc.isDir)
+		if err != nil {
+			t.Fatalf("[case %d] unexpected error: %v", i+1, err)
+		}
+		if ignored != tc.expected {
+			t.Fatalf("[case %d] unexpected ignore value for %s, expecting %v, got %v", i+1, tc.path, tc.expected, ignored)
+		}
+
+		source := ""
+		if rule != nil {
+			source, err = filepath.Rel(root, rule.Source)
+			if err != nil {
+				panic(err)
+			}
+		}
+		if source != tc.source {
+			t.Fatalf("[case %d] unexpected rule source for %s, expecting '%s', got '%s'", i+1, tc.path, tc.source, source)
+		}
+	}
+
+	s := NewWithOptions(root, filepath.Join(t.TempDir(), "dst"), "TestSsgignoreNested", "https://example.com")
+	_, _, err := s.Build(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	skipped := make(map[string]ReportSkipped)
+	for _, sk := range s.Report().Skipped {
+		rel, err := filepath.Rel(root, sk.Path)
+		if err != nil {
+			panic(err)
+		}
+		skipped[rel] = sk
+	}
+	for _, path := range []string{"drafts/draft.md", "note.txt", "blog/secret.md", "blog/deep/post.md"} {
+		sk, ok := skipped[path]
+		if !ok {
+			t.Fatalf("expecting %s to be skipped", path)
+		}
+		if sk.Reason != SkipReasonSsgIgnore || sk.Rule == "" {
+			t.Fatalf("unexpected skip for %s: %+v", path, sk)
+		}
+	}
+	for _, path := range []string{"index.md", "drafts/keep.md", "blog/note.txt", "blog/deep/keep.md"} {
+		if _, ok := skipped[path]; ok {
+			t.Fatalf("unexpected skip for %s", path)
+		}
+	}
+}
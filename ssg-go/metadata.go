@@ -44,12 +44,21 @@ func Metadata(
 	if err != nil {
 		return nil, err
 	}
+	targets := metadataTargets(dst)
 	return []OutputFile{
-		Output(filepath.Join(dst, "sitemap.xml"), "", []byte(sitemap), 0644),
-		Output(filepath.Join(dst, ".files"), "", []byte(dotFiles), 0644),
+		Output(targets[0], "", []byte(sitemap), 0644),
+		Output(targets[1], "", []byte(dotFiles), 0644),
 	}, nil
 }
 
+// metadataTargets returns targets of outputs from [Metadata]
+func metadataTargets(dst string) []string {
+	return []string{
+		filepath.Join(dst, "sitemap.xml"),
+		filepath.Join(dst, SsgDotFiles),
+	}
+}
+
 // Sitemap returns content of ${dst}/sitemap.xml
 func Sitemap(
 	dst string,
@@ -70,6 +79,12 @@ xmlns="https://www.sitemaps.org/schemas/sitemap/0.9">
 `)
 	for i := range outputs {
 		o := &outputs[i]
+		if o.redirect != "" || o.target == filepath.Join(dst, SsgRedirects) {
+			continue // Redirects are not pages
+		}
+		if o.encoding != "" {
+			continue // Precompressed siblings are served in place of their outputs
+		}
 		target, err := filepath.Rel(dst, o.target)
 		if err != nil {
 			return sm.String(), err
@@ -96,7 +111,7 @@ xmlns="https://www.sitemaps.org/schemas/sitemap/0.9">
 			sm.WriteString(target)
 		}
 
-		Fprintf(sm, "><lastmod>%s</lastmod><priority>1.0</priority></url>\n", dateStr)
+		Fprintf(sm, "</loc><lastmod>%s</lastmod><priority>1.0</priority></url>\n", dateStr)
 	}
 
 	sm.WriteString("</urlset>\n")
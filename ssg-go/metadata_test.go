@@ -0,0 +1,35 @@
+package ssg
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSitemap(t *testing.T) {
+	dst := filepath.Join(t.TempDir(), "dst")
+	outputs := []OutputFile{
+		Output(filepath.Join(dst, "index.html"), "index.md", nil, 0644),
+		Output(filepath.Join(dst, "blog", "index.html"), "blog/index.md", nil, 0644),
+		Output(filepath.Join(dst, "blog", "hello.html"), "blog/hello.md", nil, 0644),
+	}
+
+	sitemap, err := Sitemap(dst, "https://example.com", time.Date(2024, 10, 4, 0, 0, 0, 0, time.UTC), outputs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Earlier versions never closed <loc>, writing a bare '>' after URLs
+	for _, url := range []string{
+		"<url><loc>https://example.com/</loc><lastmod>2024-10-04</lastmod><priority>1.0</priority></url>\n",
+		"<url><loc>https://example.com/blog/</loc><lastmod>2024-10-04</lastmod><priority>1.0</priority></url>\n",
+		"<url><loc>https://example.com/blog/hello.html</loc><lastmod>2024-10-04</lastmod><priority>1.0</priority></url>\n",
+	} {
+		if !strings.Contains(sitemap, url) {
+			t.Fatalf("missing '%s' in sitemap:\n%s", url, sitemap)
+		}
+	}
+	if !strings.HasSuffix(sitemap, "</urlset>\n") {
+		t.Fatalf("unexpected end of sitemap:\n%s", sitemap)
+	}
+}
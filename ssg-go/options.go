@@ -3,9 +3,13 @@ package ssg
 import (
 	"fmt"
 	"io/fs"
+	"log/slog"
 	"os"
 	"reflect"
 	"strconv"
+
+	"github.com/gomarkdown/markdown/html"
+	"github.com/gomarkdown/markdown/parser"
 )
 
 type (
@@ -26,6 +30,11 @@ type (
 	// to control subsequent operations of the walk.
 	Pipeline func(path string, data []byte, d fs.DirEntry) (string, []byte, fs.DirEntry, error)
 
+	// HookOutputs takes in all outputs of a build before they are written,
+	// and returns outputs to be written, e.g. with renamed targets.
+	// With HookOutputs, outputs are only written after the whole build is done.
+	HookOutputs func(outputs []OutputFile) ([]OutputFile, error)
+
 	Options interface {
 		Hooks() []Hook
 		HooksGenerate() []HookGenerate
@@ -38,9 +47,26 @@ type (
 		// outputs      Outputs
 		hooks        []Hook
 		hookGenerate []HookGenerate
+		hookOutputs  []HookOutputs
 		pipelines    []Pipeline
 		caching      bool
 		writers      int
+		compression  Compression
+		logger       *slog.Logger
+		symlinks     SymlinkPolicy
+		dotfiles     []string
+		paths        PathStrategy
+		redirects    map[string]string
+		overwrite    bool
+		rewriteLinks bool
+		markdown     Markdown
+	}
+
+	// Markdown configures how Markdown pages are parsed and rendered into HTML.
+	// Zero values default to [SsgExtensions] and [HtmlFlags].
+	Markdown struct {
+		Extensions parser.Extensions // Parser extensions
+		Flags      html.Flags        // HTML renderer flags
 	}
 )
 
@@ -50,6 +76,20 @@ func (o options) Pipelines() []Pipeline         { return o.pipelines }
 func (o options) Caching() bool                 { return o.caching }
 func (o options) Writers() int                  { return o.writers }
 
+func (m Markdown) extensions() parser.Extensions {
+	if m.Extensions == 0 {
+		return SsgExtensions
+	}
+	return m.Extensions
+}
+
+func (m Markdown) flags() html.Flags {
+	if m.Flags == 0 {
+		return HtmlFlags
+	}
+	return m.Flags
+}
+
 // WritersFromEnv returns an option that sets the parallel writes
 // to whatever [GetEnvWriters] returns
 func WritersFromEnv() Option {
@@ -77,11 +117,89 @@ func Caching(b bool) Option {
 	return func(s *Ssg) { s.options.caching = b }
 }
 
+// AllowOverwrites allows outputs to overwrite earlier outputs with the same targets,
+// e.g. when a pipeline intentionally renames its input to an existing target.
+// By default, such duplicate targets are build errors.
+//
+// Outputs are then held until the build is done, so that only the last output
+// for each target is written, and sitemap.xml and .files always overwrite
+// other outputs. Precompressed siblings never overwrite other outputs.
+func AllowOverwrites(b bool) Option {
+	return func(s *Ssg) { s.options.overwrite = b }
+}
+
+// RewriteLinks rewrites relative links to .md sources in Markdown pages
+// to links to their actual outputs, e.g. [see](../other.md) to [see](../other.html).
+// Links to nonexistent or ignored sources are build errors.
+func RewriteLinks(b bool) Option {
+	return func(s *Ssg) { s.options.rewriteLinks = b }
+}
+
 // Writers set the number of concurrent output writers.
 func Writers(u uint) Option {
 	return func(s *Ssg) { s.options.writers = int(u) }
 }
 
+// WithMarkdown sets parser extensions and HTML renderer flags
+// used to convert Markdown pages. See [Markdown].
+func WithMarkdown(m Markdown) Option {
+	return func(s *Ssg) { s.options.markdown = m }
+}
+
+// WithCompression makes writers also write precompressed siblings
+// of outputs, e.g. index.html.gz. See [Compression].
+// Zero MinSize defaults to [CompressMinSizeDefault].
+func WithCompression(c Compression) Option {
+	if c.MinSize == 0 {
+		c.MinSize = CompressMinSizeDefault
+	}
+	return func(s *Ssg) { s.options.compression = c }
+}
+
+// WithLogger injects l as the logger for build progress, e.g. outputs written.
+// If no logger is injected, ssg-go prints plain output paths to stdout
+// like the original ssg.
+func WithLogger(l *slog.Logger) Option {
+	return func(s *Ssg) { s.options.logger = l }
+}
+
+// WithSymlinks sets how symlinks under src are handled.
+// By default, symlinks are skipped. See [SymlinkPolicy].
+func WithSymlinks(p SymlinkPolicy) Option {
+	return func(s *Ssg) { s.options.symlinks = p }
+}
+
+// AllowDotfiles allows files and directories whose names start with "."
+// matching any of patterns to be processed like other files, e.g. ".well-known".
+//
+// Patterns are [filepath.Match] patterns. Patterns without "/" are matched
+// against base names at any depth, while others are matched against paths
+// relative to src. .ssgignore and .files are reserved and are never allowed.
+func AllowDotfiles(patterns ...string) Option {
+	return func(s *Ssg) { s.options.dotfiles = append(s.options.dotfiles, patterns...) }
+}
+
+// WithPathStrategy sets output paths of Markdown pages.
+// By default, [PathsUgly] is used. See [PathStrategy].
+func WithPathStrategy(p PathStrategy) Option {
+	return func(s *Ssg) { s.options.paths = p }
+}
+
+// WithRedirects adds site-wide redirects from old URL paths to new URL paths
+// or absolute URLs. Each redirect is output as an HTML redirect stub at the old path,
+// and all redirects, including page aliases, are listed in ${dst}/_redirects.
+// See [RedirectHtml].
+func WithRedirects(redirects map[string]string) Option {
+	return func(s *Ssg) {
+		if s.options.redirects == nil {
+			s.options.redirects = make(map[string]string)
+		}
+		for from, to := range redirects {
+			s.options.redirects[from] = to
+		}
+	}
+}
+
 // func WithOutputs(c chan<- OutputFile) Option {
 // 	return func(s *Ssg) { s.options.outputs = NewOutputs(c) }
 // }
@@ -108,6 +226,12 @@ func WithHooksGenerate(hooks ...HookGenerate) Option {
 	return func(s *Ssg) { s.options.hookGenerate = append(s.options.hookGenerate, hooks...) }
 }
 
+// WithHooksOutputs will make [Ssg] call hooks sequentially
+// with all outputs of a build before writing them. See [HookOutputs].
+func WithHooksOutputs(hooks ...HookOutputs) Option {
+	return func(s *Ssg) { s.options.hookOutputs = append(s.options.hookOutputs, hooks...) }
+}
+
 // WithPipelines returns an option that allows caller
 // to set the pipeline(s) chained together for each file visit,
 // in a fashion similar to middlewares in HTTP frameworks.
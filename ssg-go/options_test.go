@@ -11,6 +11,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/gomarkdown/markdown/html"
+	"github.com/gomarkdown/markdown/parser"
+
 	"github.com/soyart/ssg/ssg-go"
 )
 
@@ -282,3 +285,114 @@ func TestChainPipelines(t *testing.T) {
 		panic(err)
 	}
 }
+
+func TestAllowOverwrites(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "src")
+	for _, name := range []string{"a.txt", "b.txt"} {
+		path := filepath.Join(src, name)
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			panic(err)
+		}
+		err = os.WriteFile(path, []byte(name), 0644)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	// Renames b.txt to a.txt, colliding with output of a.txt
+	rename := func(path string, data []byte, d fs.DirEntry) (string, []byte, fs.DirEntry, error) {
+		if filepath.Base(path) == "b.txt" {
+			return filepath.Join(filepath.Dir(path), "a.txt"), data, d, nil
+		}
+		return path, data, d, nil
+	}
+
+	s := ssg.NewWithOptions(src, filepath.Join(root, "dst"), "TestAllowOverwrites", "https://example.com",
+		ssg.Caching(true),
+		ssg.WithPipelines(rename),
+	)
+	_, _, err := s.Build(nil)
+	if err == nil {
+		t.Fatal("expecting error from duplicate targets")
+	}
+	if !strings.Contains(err.Error(), "duplicate target") || !strings.Contains(err.Error(), filepath.Join(src, "a.txt")) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s.With(ssg.AllowOverwrites(true))
+	_, outputs, err := s.Build(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(outputs) != 1 {
+		t.Fatalf("unexpected number of outputs: expecting 1, got %d", len(outputs))
+	}
+	if string(outputs[0].Data()) != "b.txt" {
+		t.Fatalf("unexpected overwritten output: %s", outputs[0].Data())
+	}
+}
+
+func TestWithMarkdown(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "src")
+	err := os.MkdirAll(src, 0755)
+	if err != nil {
+		panic(err)
+	}
+	err = os.WriteFile(filepath.Join(src, "index.md"), []byte("# Home\nfirst\nsecond [link](https://example.org)\n"), 0644)
+	if err != nil {
+		panic(err)
+	}
+
+	type testCase struct {
+		markdown    ssg.Markdown
+		expected    []string
+		notExpected []string
+	}
+
+	tests := []testCase{
+		{
+			expected:    []string{`<h1 id="home">`, "first\nsecond"},
+			notExpected: []string{"<br", `target="_blank"`},
+		},
+		{
+			markdown: ssg.Markdown{
+				Extensions: ssg.SsgExtensions | parser.HardLineBreak,
+				Flags:      ssg.HtmlFlags | html.HrefTargetBlank,
+			},
+			expected: []string{`<h1 id="home">`, "first<br", `target="_blank"`},
+		},
+		{
+			markdown: ssg.Markdown{
+				Extensions: parser.CommonExtensions,
+			},
+			expected:    []string{"<h1>Home</h1>"},
+			notExpected: []string{`id="home"`},
+		},
+	}
+
+	for i := range tests {
+		tc := &tests[i]
+		s := ssg.NewWithOptions(src, filepath.Join(root, "dst"), "TestWithMarkdown", "https://example.com",
+			ssg.Caching(true),
+			ssg.WithMarkdown(tc.markdown),
+		)
+		_, outputs, err := s.Build(nil)
+		if err != nil {
+			t.Fatalf("[%d] unexpected error: %v", i, err)
+		}
+		out := string(outputs[0].Data())
+		for _, e := range tc.expected {
+			if !strings.Contains(out, e) {
+				t.Fatalf("[%d] missing '%s' in output:\n%s", i, e, out)
+			}
+		}
+		for _, e := range tc.notExpected {
+			if strings.Contains(out, e) {
+				t.Fatalf("[%d] unexpected '%s' in output:\n%s", i, e, out)
+			}
+		}
+	}
+}
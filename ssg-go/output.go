@@ -1,6 +1,11 @@
 package ssg
 
-import "io/fs"
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
 
 // OutputFile is the main output struct for ssg-go.
 //
@@ -12,6 +17,9 @@ type OutputFile struct {
 	originator string
 	data       []byte
 	perm       fs.FileMode
+	link       string // If non-empty, target is written as a symlink to link
+	redirect   string // If non-empty, target is a redirect stub to URL redirect
+	encoding   string // If non-empty, target is a precompressed sibling with this encoding
 }
 
 // Outputs is any collection out OutputFile.
@@ -27,9 +35,15 @@ type outputsV1 struct {
 
 type buildOutput struct {
 	cacheOutput bool
-	writer      Outputs      // Main outputs
-	files       []string     // Input files read (not ignored)
-	cache       []OutputFile // Cache of main outputs
+	overwrite   bool              // Allow outputs to overwrite earlier outputs with the same targets
+	compression Compression       // Compression of outputs, whose sibling targets are also registered
+	writer      Outputs           // Main outputs
+	files       []string          // Input files read (not ignored)
+	cache       []OutputFile      // Cache of main outputs
+	targets     map[string]string // Maps output targets to their originators
+	siblings    map[string]string // Maps targets of precompressed siblings to targets of their outputs
+	reserved    map[string]bool   // Targets reserved for metadata, which is added last
+	errs        []error           // Duplicate targets rejected by Add
 }
 
 func NewOutputsStreaming(c chan<- OutputFile) Outputs {
@@ -42,11 +56,114 @@ func (o outputsV1) Add(outputs ...OutputFile) {
 	}
 }
 
+// errOverwritten is returned by [buildOutput.register] for outputs
+// which would be overwritten by metadata anyway
+var errOverwritten = errors.New("overwritten by metadata")
+
+// Add registers outputs by their targets before passing them on.
+// Outputs whose targets were already added are rejected and remembered
+// as errors, unless b allows overwrites. See [buildOutput.register].
 func (b *buildOutput) Add(outputs ...OutputFile) {
-	if b.cacheOutput {
-		b.cache = append(b.cache, outputs...)
+	for i := range outputs {
+		o := &outputs[i]
+		replaced, err := b.register(o)
+		if errors.Is(err, errOverwritten) {
+			continue
+		}
+		if err != nil {
+			b.errs = append(b.errs, err)
+			continue
+		}
+		if b.cacheOutput {
+			b.cache = b.replace(replaced, *o)
+		}
+		if b.writer != nil {
+			b.writer.Add(*o)
+		}
+	}
+}
+
+// register registers target of o, and targets of its precompressed siblings.
+// It returns whether o replaces an earlier output with the same target,
+// which is an error unless b allows overwrites.
+// Precompressed siblings never overwrite other outputs, and vice versa.
+func (b *buildOutput) register(o *OutputFile) (bool, error) {
+	if b.targets == nil {
+		b.targets = make(map[string]string)
+		b.siblings = make(map[string]string)
+	}
+	if b.reserved[o.target] {
+		if b.overwrite {
+			return false, errOverwritten // Metadata will overwrite o
+		}
+		return false, fmt.Errorf("duplicate target '%s' from '%s' and metadata", o.target, o.originator)
+	}
+	if target, ok := b.siblings[o.target]; ok {
+		return false, fmt.Errorf("duplicate target '%s' from '%s' and precompressed sibling of '%s'", o.target, o.originator, target)
+	}
+	siblings := b.compression.targets(o)
+	for _, sibling := range siblings {
+		if other, ok := b.targets[sibling]; ok {
+			return false, fmt.Errorf("duplicate target '%s' from '%s' and precompressed sibling of '%s'", sibling, other, o.target)
+		}
+	}
+	other, replaced := b.targets[o.target]
+	if replaced && !b.overwrite {
+		return false, fmt.Errorf("duplicate target '%s' from '%s' and '%s'", o.target, other, o.originator)
+	}
+
+	b.targets[o.target] = o.originator
+	for sibling, target := range b.siblings {
+		if target == o.target {
+			delete(b.siblings, sibling) // Siblings of the replaced output
+		}
+	}
+	for _, sibling := range siblings {
+		b.siblings[sibling] = o.target
+	}
+	return replaced, nil
+}
+
+// reserve reserves targets for metadata, so that other outputs
+// with the same targets are rejected before they are written
+func (b *buildOutput) reserve(targets ...string) {
+	b.reserved = make(map[string]bool)
+	for _, target := range targets {
+		b.reserved[target] = true
+	}
+}
+
+// err returns errors from duplicate targets rejected by Add
+func (b *buildOutput) err() error {
+	return errors.Join(b.errs...)
+}
+
+// replace returns cache with o replacing the cached output
+// with the same target if overwrite is true, or with o appended
+func (b *buildOutput) replace(overwrite bool, o OutputFile) []OutputFile {
+	if overwrite {
+		for i := range b.cache {
+			if b.cache[i].target == o.target {
+				b.cache[i] = o
+				return b.cache
+			}
+		}
+	}
+	return append(b.cache, o)
+}
+
+// write writes o to its target. Existing symlinks at the target are replaced,
+// so that we never write through a symlink into its target.
+func (o *OutputFile) write() error {
+	stat, err := os.Lstat(o.target)
+	if err == nil && (o.link != "" || FileIs(stat, fs.ModeSymlink)) {
+		err = os.Remove(o.target)
+		if err != nil {
+			return err
+		}
 	}
-	if b.writer != nil {
-		b.writer.Add(outputs...)
+	if o.link != "" {
+		return os.Symlink(o.link, o.target)
 	}
+	return os.WriteFile(o.target, o.data, o.Perm())
 }
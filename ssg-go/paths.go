@@ -0,0 +1,86 @@
+package ssg
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// PathStrategy determines output paths of pages converted from Markdown
+type PathStrategy string
+
+const (
+	// PathsUgly outputs page foo/bar.md to foo/bar.html,
+	// as with the original ssg. This is the default strategy.
+	PathsUgly PathStrategy = "ugly"
+
+	// PathsPretty outputs page foo/bar.md to foo/bar/index.html,
+	// so that the page can be accessed with extensionless URL /foo/bar/.
+	// Index pages (index.md) and 404 pages (404.md) are output as with PathsUgly.
+	PathsPretty PathStrategy = "pretty"
+)
+
+// ParsePathStrategy parses s into a [PathStrategy].
+// An empty string is parsed as [PathsUgly].
+func ParsePathStrategy(s string) (PathStrategy, error) {
+	switch p := PathStrategy(s); p {
+	case "":
+		return PathsUgly, nil
+	case PathsUgly, PathsPretty:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown path strategy '%s', expecting one of %s or %s", s, PathsUgly, PathsPretty)
+}
+
+// Page returns the HTML output path for Markdown page at path.
+// path may be relative or absolute, and the result will be the same kind.
+func (p PathStrategy) Page(path string) string {
+	path = ChangeExt(path, ".md", ".html")
+	if p != PathsPretty {
+		return path
+	}
+	switch filepath.Base(path) {
+	case "index.html", "404.html":
+		return path
+	}
+	return filepath.Join(strings.TrimSuffix(path, ".html"), "index.html")
+}
+
+// Link returns URL path of Markdown page at path relative to src,
+// without the leading slash. With PathsPretty, the link ends with "/".
+func (p PathStrategy) Link(path string) string {
+	return p.link(p.Page(path))
+}
+
+// link returns URL path of page output at page relative to dst
+func (p PathStrategy) link(page string) string {
+	link := filepath.ToSlash(page)
+	if p != PathsPretty || filepath.Base(link) != "index.html" {
+		return link
+	}
+	dir := filepath.ToSlash(filepath.Dir(link))
+	if dir == "." {
+		return ""
+	}
+	return dir + "/"
+}
+
+// preferHtml returns whether an HTML file under s.Src should be preferred
+// over Markdown page at path with output target, either because they share
+// the same name as with the original ssg, or because their output paths collide.
+//
+// Collisions with pages with custom slugs or permalinks are not resolved here,
+// and are instead reported as errors by claimPage.
+func (s *Ssg) preferHtml(path, target string, custom bool) (bool, error) {
+	if s.preferred.Contains(ChangeExt(path, ".md", ".html")) {
+		return true, nil
+	}
+	if custom {
+		return false, nil
+	}
+	rel, err := filepath.Rel(s.Dst, target)
+	if err != nil {
+		return false, err
+	}
+	return s.preferred.Contains(filepath.Join(s.Src, rel)), nil
+}
@@ -0,0 +1,153 @@
+package ssg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPathStrategy(t *testing.T) {
+	type testCase struct {
+		path   string
+		ugly   [2]string // Page and link
+		pretty [2]string
+	}
+
+	tests := []testCase{
+		{
+			path:   "foo.md",
+			ugly:   [2]string{"foo.html", "foo.html"},
+			pretty: [2]string{"foo/index.html", "foo/"},
+		},
+		{
+			path:   "blog/foo.md",
+			ugly:   [2]string{"blog/foo.html", "blog/foo.html"},
+			pretty: [2]string{"blog/foo/index.html", "blog/foo/"},
+		},
+		{
+			path:   "blog/index.md",
+			ugly:   [2]string{"blog/index.html", "blog/index.html"},
+			pretty: [2]string{"blog/index.html", "blog/"},
+		},
+		{
+			path:   "index.md",
+			ugly:   [2]string{"index.html", "index.html"},
+			pretty: [2]string{"index.html", ""},
+		},
+		{
+			path:   "404.md",
+			ugly:   [2]string{"404.html", "404.html"},
+			pretty: [2]string{"404.html", "404.html"},
+		},
+	}
+
+	for i := range tests {
+		tc := &tests[i]
+		for p, expected := range map[PathStrategy][2]string{PathsUgly: tc.ugly, PathsPretty: tc.pretty} {
+			page, link := p.Page(tc.path), p.Link(tc.path)
+			if page != expected[0] {
+				t.Fatalf("[case %d] unexpected %s page for '%s': expecting '%s', got '%s'", i+1, p, tc.path, expected[0], page)
+			}
+			if link != expected[1] {
+				t.Fatalf("[case %d] unexpected %s link for '%s': expecting '%s', got '%s'", i+1, p, tc.path, expected[1], link)
+			}
+		}
+	}
+
+	_, err := ParsePathStrategy("bad")
+	if err == nil {
+		t.Fatal("expecting error from bad path strategy")
+	}
+}
+
+func TestPathsPretty(t *testing.T) {
+	root := t.TempDir()
+	src, dst := filepath.Join(root, "src"), filepath.Join(root, "dst")
+	files := map[string]string{
+		"index.md":            "# Index",
+		"about.md":            "# About",
+		"blog/index.md":       "# Blog",
+		"blog/foo.md":         "# Foo",
+		"blog/bar.md":         "# Bar from Markdown",
+		"blog/bar/index.html": "<h1>Bar from HTML</h1>",
+		"blog/baz.md":         "# Baz from Markdown",
+		"blog/baz.html":       "<h1>Baz from HTML</h1>",
+		"404.md":              "# Not found",
+	}
+	for path, content := range files {
+		path = filepath.Join(src, path)
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			panic(err)
+		}
+		err = os.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	s := NewWithOptions(src, dst, "TestPathsPretty", "https://example.com",
+		Caching(true),
+		WithPathStrategy(PathsPretty),
+	)
+	_, outputs, err := s.Build(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	originators := make(map[string]string)
+	for _, o := range outputs {
+		target, err := filepath.Rel(dst, o.target)
+		if err != nil {
+			panic(err)
+		}
+		originator, err := filepath.Rel(src, o.originator)
+		if err != nil {
+			panic(err)
+		}
+		originators[filepath.ToSlash(target)] = filepath.ToSlash(originator)
+	}
+
+	expected := map[string]string{
+		"index.html":          "index.md",
+		"about/index.html":    "about.md",
+		"blog/index.html":     "blog/index.md",
+		"blog/foo/index.html": "blog/foo.md",
+		"404.html":            "404.md",
+
+		// HTML files are preferred over Markdown pages with colliding outputs,
+		// and such Markdown files are copied as-is
+		"blog/bar/index.html": "blog/bar/index.html",
+		"blog/bar.md":         "blog/bar.md",
+
+		// HTML files are not moved, and are still preferred
+		// over Markdown files with the same name
+		"blog/baz.html": "blog/baz.html",
+		"blog/baz.md":   "blog/baz.md",
+	}
+	if len(originators) != len(expected) {
+		t.Fatalf("unexpected outputs: %+v", originators)
+	}
+	for target, originator := range expected {
+		if originators[target] != originator {
+			t.Fatalf("unexpected originator for '%s': expecting '%s', got '%s'", target, originator, originators[target])
+		}
+	}
+
+	sitemap, err := Sitemap(dst, s.Url, time.Now(), outputs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, loc := range []string{
+		"<loc>https://example.com/</loc>",
+		"<loc>https://example.com/about/</loc>",
+		"<loc>https://example.com/blog/foo/</loc>",
+		"<loc>https://example.com/blog/bar/</loc>",
+	} {
+		if !strings.Contains(sitemap, loc) {
+			t.Fatalf("missing '%s' in sitemap:\n%s", loc, sitemap)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package ssg
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	keySlug      = ":ssg-slug "      // The first line starting with :ssg-slug replaces page filename
+	keyPermalink = ":ssg-permalink " // The first line starting with :ssg-permalink replaces page output path
+)
+
+// GetSlug returns value of the first :ssg-slug tag in markdown
+func GetSlug(markdown []byte) []byte {
+	return getTag(markdown, keySlug)
+}
+
+// GetPermalink returns value of the first :ssg-permalink tag in markdown
+func GetPermalink(markdown []byte) []byte {
+	return getTag(markdown, keyPermalink)
+}
+
+// PageOf is like [PathStrategy.Page], but honors :ssg-slug and :ssg-permalink
+// tags in markdown. If both are present, :ssg-permalink wins.
+//
+// Slug replaces the filename of the page, e.g. page blog/2024-03-hello.md
+// with ":ssg-slug hello" is output to blog/hello.html.
+//
+// Permalink is the output path relative to the site root:
+// a permalink with trailing slash is output to index.html in that directory,
+// a permalink with extension is output to that exact path,
+// and other permalinks are treated like pages without extension.
+func (p PathStrategy) PageOf(path string, markdown []byte) (string, error) {
+	if permalink := GetPermalink(markdown); len(permalink) != 0 {
+		return p.permalink(string(permalink))
+	}
+	if slug := GetSlug(markdown); len(slug) != 0 {
+		s := string(bytes.TrimSpace(slug))
+		if s == "." || s == ".." || strings.ContainsAny(s, `/\`) {
+			return "", fmt.Errorf("bad slug '%s'", s)
+		}
+		return p.Page(filepath.Join(filepath.Dir(path), s+".md")), nil
+	}
+	return p.Page(path), nil
+}
+
+// LinkOf is like [PathStrategy.Link], but honors :ssg-slug and :ssg-permalink
+// tags in markdown. See [PathStrategy.PageOf].
+func (p PathStrategy) LinkOf(path string, markdown []byte) (string, error) {
+	page, err := p.PageOf(path, markdown)
+	if err != nil {
+		return "", err
+	}
+	return p.link(page), nil
+}
+
+func (p PathStrategy) permalink(permalink string) (string, error) {
+	permalink = strings.TrimSpace(permalink)
+	for _, part := range strings.Split(permalink, "/") {
+		if part == ".." {
+			return "", fmt.Errorf("bad permalink '%s'", permalink)
+		}
+	}
+
+	page := strings.TrimPrefix(path.Clean("/"+permalink), "/")
+	switch {
+	case page == "", strings.HasSuffix(permalink, "/"):
+		page = path.Join(page, "index.html")
+	case path.Ext(page) == "":
+		page = p.Page(page + ".md")
+	}
+	return filepath.FromSlash(page), nil
+}
+
+// pageTarget returns output path under s.Dst of Markdown page at path
+// under s.Src, and whether the page has a custom slug or permalink
+func (s *Ssg) pageTarget(path string, markdown []byte) (string, bool, error) {
+	rel, err := filepath.Rel(s.Src, path)
+	if err != nil {
+		return "", false, err
+	}
+	page, err := s.options.paths.PageOf(rel, markdown)
+	if err != nil {
+		return "", false, fmt.Errorf("bad output path for page '%s': %w", path, err)
+	}
+	custom := len(GetPermalink(markdown)) != 0 || len(GetSlug(markdown)) != 0
+	return filepath.Join(s.Dst, page), custom, nil
+}
+
+// claimPage registers target as output of Markdown page at path,
+// returning error if target is already claimed by another page or HTML file
+func (s *Ssg) claimPage(target, path string) error {
+	rel, err := filepath.Rel(s.Dst, target)
+	if err != nil {
+		return err
+	}
+	html := filepath.Join(s.Src, rel)
+	if s.preferred.Contains(html) {
+		return fmt.Errorf("duplicate target '%s' from '%s' and '%s'", target, html, path)
+	}
+	other, ok := s.pages[target]
+	if ok {
+		return fmt.Errorf("duplicate target '%s' from '%s' and '%s'", target, other, path)
+	}
+	s.pages[target] = path
+	return nil
+}
+
+func getTag(markdown []byte, key string) []byte {
+	k := []byte(key)
+	s := bufio.NewScanner(bytes.NewBuffer(markdown))
+	for s.Scan() {
+		line := s.Bytes()
+		if !bytes.HasPrefix(line, k) {
+			continue
+		}
+		return bytes.TrimSpace(line[len(k):])
+	}
+	return nil
+}
+
+// removeTag removes the first line starting with key from markdown
+func removeTag(markdown []byte, key string) []byte {
+	k := []byte(key)
+	offset := 0
+	for offset < len(markdown) {
+		end := bytes.IndexByte(markdown[offset:], '\n')
+		next := len(markdown)
+		if end != -1 {
+			next = offset + end + 1
+		}
+		if bytes.HasPrefix(markdown[offset:], k) {
+			return append(markdown[:offset:offset], markdown[next:]...)
+		}
+		offset = next
+	}
+	return markdown
+}
@@ -0,0 +1,174 @@
+package ssg
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPageOf(t *testing.T) {
+	type testCase struct {
+		path     string
+		markdown string
+		ugly     string
+		pretty   string
+		err      bool
+	}
+
+	tests := []testCase{
+		{
+			path:     "blog/2024-03-hello.md",
+			markdown: "# Hello",
+			ugly:     "blog/2024-03-hello.html",
+			pretty:   "blog/2024-03-hello/index.html",
+		},
+		{
+			path:     "blog/2024-03-hello.md",
+			markdown: ":ssg-slug hello\n\n# Hello",
+			ugly:     "blog/hello.html",
+			pretty:   "blog/hello/index.html",
+		},
+		{
+			path:     "blog/2024-03-hello.md",
+			markdown: ":ssg-permalink /2024/03/hello/\n\n# Hello",
+			ugly:     "2024/03/hello/index.html",
+			pretty:   "2024/03/hello/index.html",
+		},
+		{
+			path:     "blog/2024-03-hello.md",
+			markdown: ":ssg-permalink /2024/03/hello\n:ssg-slug ignored\n# Hello",
+			ugly:     "2024/03/hello.html",
+			pretty:   "2024/03/hello/index.html",
+		},
+		{
+			path:     "blog/2024-03-hello.md",
+			markdown: ":ssg-permalink /hello.htm\n# Hello",
+			ugly:     "hello.htm",
+			pretty:   "hello.htm",
+		},
+		{
+			path:     "blog/2024-03-hello.md",
+			markdown: ":ssg-permalink /\n# Home",
+			ugly:     "index.html",
+			pretty:   "index.html",
+		},
+		{
+			path:     "blog/2024-03-hello.md",
+			markdown: "# Hello\n\n    :ssg-slug not-a-tag",
+			ugly:     "blog/2024-03-hello.html",
+			pretty:   "blog/2024-03-hello/index.html",
+		},
+		{
+			path:     "blog/2024-03-hello.md",
+			markdown: ":ssg-slug ../escape\n# Hello",
+			err:      true,
+		},
+		{
+			path:     "blog/2024-03-hello.md",
+			markdown: ":ssg-permalink /../escape/\n# Hello",
+			err:      true,
+		},
+	}
+
+	for i := range tests {
+		tc := &tests[i]
+		for p, expected := range map[PathStrategy]string{PathsUgly: tc.ugly, PathsPretty: tc.pretty} {
+			page, err := p.PageOf(tc.path, []byte(tc.markdown))
+			if tc.err {
+				if err == nil {
+					t.Fatalf("[case %d] expecting error for %s", i+1, p)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("[case %d] unexpected error: %v", i+1, err)
+			}
+			if filepath.ToSlash(page) != expected {
+				t.Fatalf("[case %d] unexpected %s page: expecting '%s', got '%s'", i+1, p, expected, page)
+			}
+		}
+	}
+}
+
+func TestPermalinks(t *testing.T) {
+	build := func(t *testing.T, files map[string]string) (*Ssg, []OutputFile, error) {
+		root := t.TempDir()
+		src := filepath.Join(root, "src")
+		for path, content := range files {
+			path = filepath.Join(src, path)
+			err := os.MkdirAll(filepath.Dir(path), 0755)
+			if err != nil {
+				panic(err)
+			}
+			err = os.WriteFile(path, []byte(content), 0644)
+			if err != nil {
+				panic(err)
+			}
+		}
+		s := NewWithOptions(src, filepath.Join(root, "dst"), "TestPermalinks", "https://example.com", Caching(true))
+		_, outputs, err := s.Build(nil)
+		return s, outputs, err
+	}
+
+	t.Run("custom targets", func(t *testing.T) {
+		s, outputs, err := build(t, map[string]string{
+			"blog/2024-03-hello.md": ":ssg-slug hello\n\n# Hello",
+			"blog/world.md":         "# World\n:ssg-permalink /2024/03/world/\n",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		expected := map[string]string{
+			filepath.Join(s.Dst, "blog/hello.html"):          "Hello",
+			filepath.Join(s.Dst, "2024/03/world/index.html"): "World",
+		}
+		if len(outputs) != len(expected) {
+			t.Fatalf("unexpected outputs: %+v", outputs)
+		}
+		for _, o := range outputs {
+			content, ok := expected[o.target]
+			if !ok {
+				t.Fatalf("unexpected target '%s'", o.target)
+			}
+			if !bytes.Contains(o.data, []byte(content)) {
+				t.Fatalf("unexpected content for '%s': %s", o.target, o.data)
+			}
+			if bytes.Contains(o.data, []byte(":ssg-")) {
+				t.Fatalf("unexpected tag in output '%s': %s", o.target, o.data)
+			}
+		}
+	})
+
+	t.Run("duplicate pages", func(t *testing.T) {
+		_, _, err := build(t, map[string]string{
+			"blog/a.md": ":ssg-slug same\n# A",
+			"blog/b.md": ":ssg-slug same\n# B",
+		})
+		if err == nil {
+			t.Fatal("expecting error from duplicate targets")
+		}
+		for _, originator := range []string{"blog/a.md", "blog/b.md"} {
+			if !strings.Contains(err.Error(), filepath.FromSlash(originator)) {
+				t.Fatalf("missing originator '%s' in error: %v", originator, err)
+			}
+		}
+	})
+
+	t.Run("duplicate html", func(t *testing.T) {
+		_, _, err := build(t, map[string]string{
+			"about.html": "<h1>About</h1>",
+			"blog/a.md":  ":ssg-permalink /about.html\n# A",
+		})
+		if err == nil {
+			t.Fatal("expecting error from duplicate targets")
+		}
+		for _, originator := range []string{"about.html", "blog/a.md"} {
+			if !strings.Contains(err.Error(), filepath.FromSlash(originator)) {
+				t.Fatalf("missing originator '%s' in error: %v", originator, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,181 @@
+package ssg
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"html"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// keyAlias lines list former paths of a page, one path per line.
+// Unlike other tags, all lines starting with :ssg-alias are used.
+const keyAlias = ":ssg-alias "
+
+type redirect struct {
+	from string
+	to   string
+}
+
+// GetAliases returns values of all :ssg-alias tags in markdown
+func GetAliases(markdown []byte) [][]byte {
+	k := []byte(keyAlias)
+	var aliases [][]byte
+	s := bufio.NewScanner(bytes.NewBuffer(markdown))
+	for s.Scan() {
+		line := s.Bytes()
+		if !bytes.HasPrefix(line, k) {
+			continue
+		}
+		alias := bytes.TrimSpace(line[len(k):])
+		if len(alias) != 0 {
+			aliases = append(aliases, bytes.Clone(alias))
+		}
+	}
+	return aliases
+}
+
+// RedirectHtml returns a small HTML page redirecting browsers to url
+// with meta refresh, with url as its canonical link for search engines.
+func RedirectHtml(url string) []byte {
+	u := html.EscapeString(url)
+	b := bytes.NewBuffer(nil)
+	Fprintf(b, `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Redirecting to %s</title>
+<link rel="canonical" href="%s">
+<meta http-equiv="refresh" content="0; url=%s">
+<meta name="robots" content="noindex">
+</head>
+<body>
+<p>This page has moved to <a href="%s">%s</a>.</p>
+</body>
+</html>
+`, u, u, u, u, u)
+	return b.Bytes()
+}
+
+// Redirects returns content of ${dst}/_redirects, a plain text file
+// understood by some static hosts, with one "from to 301" rule per line
+func Redirects(redirects map[string]string) string {
+	froms := make([]string, 0, len(redirects))
+	for from := range redirects {
+		froms = append(froms, from)
+	}
+	sort.Strings(froms)
+
+	b := bytes.NewBuffer(nil)
+	for _, from := range froms {
+		Fprintf(b, "%s %s 301\n", from, redirects[from])
+	}
+	return b.String()
+}
+
+// alias outputs redirect stubs at aliases of Markdown page at path,
+// redirecting to the page's output at target
+func (s *Ssg) alias(target, path string, aliases [][]byte) error {
+	rel, err := filepath.Rel(s.Dst, target)
+	if err != nil {
+		return err
+	}
+	to := "/" + s.options.paths.link(rel)
+	for _, alias := range aliases {
+		err := s.redirect(redirectFrom(string(alias)), to, path)
+		if err != nil {
+			return fmt.Errorf("bad alias for page '%s': %w", path, err)
+		}
+	}
+	return nil
+}
+
+// redirect outputs a redirect stub at from, redirecting to to.
+// from is a URL path, while to may be a URL path or an absolute URL.
+func (s *Ssg) redirect(from, to, originator string) error {
+	page, err := s.options.paths.permalink(from)
+	if err != nil {
+		return err
+	}
+	target := filepath.Join(s.Dst, page)
+	err = s.claimPage(target, originator)
+	if err != nil {
+		return err
+	}
+
+	url := to
+	if !strings.Contains(to, "://") {
+		url = strings.TrimSuffix(s.Url, "/") + to
+	}
+	s.redirects = append(s.redirects, redirect{from: from, to: to})
+	s.result.Add(OutputRedirect(target, originator, url))
+	return nil
+}
+
+// buildRedirects outputs stubs for site-wide redirects, and ${dst}/_redirects
+// listing all redirects if there is any site-wide redirect
+func (s *Ssg) buildRedirects() error {
+	if len(s.options.redirects) == 0 {
+		return nil
+	}
+	if slices.Contains(s.result.files, filepath.Join(s.Src, SsgRedirects)) {
+		return fmt.Errorf("site-wide redirects conflict with '%s'", filepath.Join(s.Src, SsgRedirects))
+	}
+
+	redirects := make(map[string]string)
+	for _, r := range s.redirects {
+		redirects[r.from] = r.to
+	}
+	for from, to := range s.options.redirects {
+		from = redirectFrom(from)
+		to = strings.TrimSpace(to)
+		if to == "" {
+			return fmt.Errorf("empty redirect target for '%s'", from)
+		}
+		if !strings.Contains(to, "://") && !strings.HasPrefix(to, "/") {
+			to = "/" + to
+		}
+		if _, ok := redirects[from]; ok {
+			return fmt.Errorf("duplicate redirect from '%s'", from)
+		}
+		redirects[from] = to
+	}
+
+	froms := make([]string, 0, len(s.options.redirects))
+	for from := range s.options.redirects {
+		froms = append(froms, redirectFrom(from))
+	}
+	sort.Strings(froms)
+	for _, from := range froms {
+		err := s.redirect(from, redirects[from], "redirect "+from)
+		if err != nil {
+			return fmt.Errorf("bad redirect from '%s': %w", from, err)
+		}
+	}
+
+	s.result.Add(Output(filepath.Join(s.Dst, SsgRedirects), "", []byte(Redirects(redirects)), 0644))
+	return nil
+}
+
+// redirectFrom returns URL path from with leading slash
+func redirectFrom(from string) string {
+	from = strings.TrimSpace(from)
+	if !strings.HasPrefix(from, "/") {
+		from = "/" + from
+	}
+	return from
+}
+
+// removeTags removes all lines starting with key from markdown
+func removeTags(markdown []byte, key string) []byte {
+	for {
+		l := len(markdown)
+		markdown = removeTag(markdown, key)
+		if len(markdown) == l {
+			return markdown
+		}
+	}
+}
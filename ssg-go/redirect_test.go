@@ -0,0 +1,169 @@
+package ssg
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedirects(t *testing.T) {
+	build := func(t *testing.T, files map[string]string, opts ...Option) (*Ssg, map[string]OutputFile, error) {
+		root := t.TempDir()
+		src := filepath.Join(root, "src")
+		for path, content := range files {
+			path = filepath.Join(src, path)
+			err := os.MkdirAll(filepath.Dir(path), 0755)
+			if err != nil {
+				panic(err)
+			}
+			err = os.WriteFile(path, []byte(content), 0644)
+			if err != nil {
+				panic(err)
+			}
+		}
+		s := NewWithOptions(src, filepath.Join(root, "dst"), "TestRedirects", "https://example.com",
+			append([]Option{Caching(true)}, opts...)...,
+		)
+		_, cache, err := s.Build(nil)
+		outputs := make(map[string]OutputFile)
+		for _, o := range cache {
+			rel, err := filepath.Rel(s.Dst, o.target)
+			if err != nil {
+				panic(err)
+			}
+			outputs[filepath.ToSlash(rel)] = o
+		}
+		return s, outputs, err
+	}
+
+	t.Run("aliases", func(t *testing.T) {
+		s, outputs, err := build(t, map[string]string{
+			"blog/hello.md": ":ssg-alias /2023/hello.html\n:ssg-alias old/hello/\n# Hello",
+		}, WithPathStrategy(PathsPretty))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		page, ok := outputs["blog/hello/index.html"]
+		if !ok {
+			t.Fatalf("missing page: %+v", outputs)
+		}
+		if bytes.Contains(page.data, []byte(":ssg-alias")) {
+			t.Fatalf("unexpected tag in page: %s", page.data)
+		}
+		for _, stub := range []string{"2023/hello.html", "old/hello/index.html"} {
+			o, ok := outputs[stub]
+			if !ok {
+				t.Fatalf("missing redirect stub %s", stub)
+			}
+			if o.Redirect() != "https://example.com/blog/hello/" {
+				t.Fatalf("unexpected redirect for %s: '%s'", stub, o.Redirect())
+			}
+			for _, expected := range []string{
+				`<link rel="canonical" href="https://example.com/blog/hello/">`,
+				`<meta http-equiv="refresh" content="0; url=https://example.com/blog/hello/">`,
+			} {
+				if !bytes.Contains(o.data, []byte(expected)) {
+					t.Fatalf("missing '%s' in stub %s:\n%s", expected, stub, o.data)
+				}
+			}
+			if o.originator != filepath.Join(s.Src, "blog", "hello.md") {
+				t.Fatalf("unexpected originator for %s: %s", stub, o.originator)
+			}
+		}
+		if _, ok := outputs[SsgRedirects]; ok {
+			t.Fatalf("unexpected %s without site-wide redirects", SsgRedirects)
+		}
+
+		sitemap, err := Sitemap(s.Dst, s.Url, time.Now(), mapValues(outputs))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if strings.Contains(sitemap, "2023") || strings.Contains(sitemap, "old/hello") {
+			t.Fatalf("unexpected redirect stubs in sitemap:\n%s", sitemap)
+		}
+	})
+
+	t.Run("site-wide", func(t *testing.T) {
+		_, outputs, err := build(t, map[string]string{
+			"blog/hello.md": ":ssg-alias /old-hello.html\n# Hello",
+		}, WithRedirects(map[string]string{
+			"/feed.xml": "/blog/feed.xml",
+			"old-blog/": "/blog/",
+			"/mastodon": "https://mastodon.example/@johndoe",
+		}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		expected := map[string]string{
+			"old-hello.html":      "https://example.com/blog/hello.html",
+			"feed.xml":            "https://example.com/blog/feed.xml",
+			"old-blog/index.html": "https://example.com/blog/",
+			"mastodon.html":       "https://mastodon.example/@johndoe",
+		}
+		for stub, url := range expected {
+			o, ok := outputs[stub]
+			if !ok {
+				t.Fatalf("missing redirect stub %s", stub)
+			}
+			if o.Redirect() != url {
+				t.Fatalf("unexpected redirect for %s: expecting '%s', got '%s'", stub, url, o.Redirect())
+			}
+		}
+
+		redirects, ok := outputs[SsgRedirects]
+		if !ok {
+			t.Fatalf("missing %s", SsgRedirects)
+		}
+		expectedRedirects := `/feed.xml /blog/feed.xml 301
+/mastodon https://mastodon.example/@johndoe 301
+/old-blog/ /blog/ 301
+/old-hello.html /blog/hello.html 301
+`
+		if string(redirects.data) != expectedRedirects {
+			t.Fatalf("unexpected %s:\n%s", SsgRedirects, redirects.data)
+		}
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		_, _, err := build(t, map[string]string{
+			"blog/a.md": ":ssg-alias /old.html\n# A",
+			"blog/b.md": "# B\n:ssg-alias /old.html",
+		})
+		if err == nil {
+			t.Fatal("expecting error from duplicate aliases")
+		}
+		for _, originator := range []string{"blog/a.md", "blog/b.md"} {
+			if !strings.Contains(err.Error(), filepath.FromSlash(originator)) {
+				t.Fatalf("missing originator '%s' in error: %v", originator, err)
+			}
+		}
+
+		_, _, err = build(t, map[string]string{
+			"about.md": ":ssg-alias /index.html\n# About",
+			"index.md": "# Index",
+		})
+		if err == nil {
+			t.Fatal("expecting error from alias colliding with page")
+		}
+
+		_, _, err = build(t, map[string]string{
+			"blog/a.md": ":ssg-alias /old.html\n# A",
+		}, WithRedirects(map[string]string{"/old.html": "/new.html"}))
+		if err == nil {
+			t.Fatal("expecting error from redirect colliding with alias")
+		}
+
+		_, _, err = build(t, map[string]string{
+			"index.md":   "# Index",
+			SsgRedirects: "/foo /bar 301",
+		}, WithRedirects(map[string]string{"/old.html": "/new.html"}))
+		if err == nil {
+			t.Fatalf("expecting error from %s in src", SsgRedirects)
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package ssg
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	SkipReasonDot          = "dotfile"
+	SkipReasonDir          = "directory"
+	SkipReasonSsgIgnore    = "ssgignore"
+	SkipReasonSymlink      = "symlink"
+	SkipReasonSymlinkCycle = "symlink-cycle"
+	SkipReasonNotExist     = "not-exist"
+	SkipReasonMarker       = "marker"
+	SkipReasonSkipCore     = "pipeline-skip-core"
+)
+
+// Report describes what ssg-go did during a build,
+// and is meant to be marshaled into JSON for machine consumption.
+//
+// Report is populated by [Ssg.Build] and [Ssg.Generate],
+// and can be retrieved afterwards via [Ssg.Report].
+type Report struct {
+	mut sync.Mutex
+
+	Src      string          `json:"src"`
+	Dst      string          `json:"dst"`
+	Inputs   []string        `json:"inputs"`
+	Outputs  []ReportOutput  `json:"outputs"`
+	Skipped  []ReportSkipped `json:"skipped"`
+	Timings  ReportTimings   `json:"timings"`
+	Warnings []string        `json:"warnings"`
+}
+
+// ReportOutput is an output file written to disk
+type ReportOutput struct {
+	Target     string `json:"target"`
+	Originator string `json:"originator"`
+	Size       int    `json:"size"`
+}
+
+// ReportSkipped is a file under src that was not sent to core
+type ReportSkipped struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+	Rule   string `json:"rule,omitempty"` // .ssgignore rule, if ignored by one
+}
+
+// ReportTimings records durations of build steps in nanoseconds.
+// Pipelines, Hooks, HooksGenerate and HooksOutputs are cumulative durations
+// of each option, indexed in the order they were given to [Ssg].
+type ReportTimings struct {
+	Total         time.Duration   `json:"total_ns"`
+	Build         time.Duration   `json:"build_ns"`
+	Write         time.Duration   `json:"write_ns"`
+	Metadata      time.Duration   `json:"metadata_ns"`
+	Pipelines     []time.Duration `json:"pipelines_ns"`
+	Hooks         []time.Duration `json:"hooks_ns"`
+	HooksGenerate []time.Duration `json:"hooks_generate_ns"`
+	HooksOutputs  []time.Duration `json:"hooks_outputs_ns"`
+}
+
+func newReport(s *Ssg) *Report {
+	return &Report{
+		Src:      s.Src,
+		Dst:      s.Dst,
+		Inputs:   []string{},
+		Outputs:  []ReportOutput{},
+		Skipped:  []ReportSkipped{},
+		Warnings: []string{},
+		Timings: ReportTimings{
+			Pipelines:     make([]time.Duration, len(s.options.pipelines)),
+			Hooks:         make([]time.Duration, len(s.options.hooks)),
+			HooksGenerate: make([]time.Duration, len(s.options.hookGenerate)),
+			HooksOutputs:  make([]time.Duration, len(s.options.hookOutputs)),
+		},
+	}
+}
+
+func (r *Report) input(path string) {
+	r.Inputs = append(r.Inputs, path)
+}
+
+func (r *Report) skip(path string, reason string, rule *IgnoreRule) {
+	skipped := ReportSkipped{Path: path, Reason: reason}
+	if rule != nil {
+		skipped.Rule = rule.String()
+	}
+	r.Skipped = append(r.Skipped, skipped)
+}
+
+func (r *Report) warn(format string, args ...any) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// output is called concurrently by writers
+func (r *Report) output(o *OutputFile) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	r.Outputs = append(r.Outputs, ReportOutput{
+		Target:     o.target,
+		Originator: o.originator,
+		Size:       len(o.data),
+	})
+}
@@ -0,0 +1,128 @@
+package ssg
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gomarkdown/markdown"
+	"github.com/gomarkdown/markdown/ast"
+	"github.com/gomarkdown/markdown/html"
+	"github.com/gomarkdown/markdown/parser"
+)
+
+// toHtml converts Markdown page at path into HTML, like [ToHtml]
+// but with Markdown options of s, see [WithMarkdown].
+// If link rewriting is enabled, relative links to .md sources are rewritten
+// to links to their outputs, relative to the page's output at target.
+func (s *Ssg) toHtml(path, target string, md []byte) ([]byte, error) {
+	m := s.options.markdown
+	root := markdown.Parse(md, parser.NewWithExtensions(m.extensions()))
+	renderer := html.NewRenderer(html.RendererOptions{
+		Flags: m.flags(),
+	})
+	if !s.options.rewriteLinks {
+		return markdown.Render(root, renderer), nil
+	}
+
+	var err error
+	ast.WalkFunc(root, func(node ast.Node, entering bool) ast.WalkStatus {
+		link, ok := node.(*ast.Link)
+		if !ok || !entering {
+			return ast.GoToNext
+		}
+		var dest string
+		dest, err = s.rewriteLink(path, target, string(link.Destination))
+		if err != nil {
+			return ast.Terminate
+		}
+		link.Destination = []byte(dest)
+		return ast.GoToNext
+	})
+	if err != nil {
+		return nil, err
+	}
+	return markdown.Render(root, renderer), nil
+}
+
+// rewriteLink returns link in Markdown page at path, with output at target,
+// rewritten to the output of the linked .md source. Links to other files,
+// absolute links and links to other sites are returned as they are.
+func (s *Ssg) rewriteLink(path, target, link string) (string, error) {
+	u, err := url.Parse(link)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Opaque != "" {
+		return link, nil
+	}
+	if u.Path == "" || strings.HasPrefix(u.Path, "/") || filepath.Ext(u.Path) != ".md" {
+		return link, nil
+	}
+
+	source := filepath.Join(filepath.Dir(path), filepath.FromSlash(u.Path))
+	linked, err := s.linkedPage(source)
+	if err != nil {
+		return "", fmt.Errorf("bad link '%s' in page '%s': %w", link, path, err)
+	}
+
+	rel, err := filepath.Rel(filepath.Dir(target), linked)
+	if err != nil {
+		return "", err
+	}
+	rel = filepath.ToSlash(rel)
+	if s.options.paths == PathsPretty && filepath.Base(linked) == "index.html" {
+		rel = strings.TrimSuffix(rel, "index.html")
+		if rel == "" {
+			rel = "./"
+		}
+	}
+
+	u.Path = rel
+	u.RawPath = ""
+	return u.String(), nil
+}
+
+// linkedPage returns output path under s.Dst of source, a .md file
+// linked from another page, honoring preferred HTML files.
+func (s *Ssg) linkedPage(source string) (string, error) {
+	rel, err := filepath.Rel(s.Src, source)
+	if err != nil {
+		return "", err
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("source '%s' is outside of src", source)
+	}
+	data, err := os.ReadFile(source)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("nonexistent source '%s'", source)
+		}
+		return "", err
+	}
+	if s.Ignore(source) {
+		return "", fmt.Errorf("ignored source '%s'", source)
+	}
+
+	// HTML files with the same name are preferred, as with preferHtml
+	html := ChangeExt(source, ".md", ".html")
+	if s.sourceExists(html) {
+		return mirrorPath(s.Src, s.Dst, html)
+	}
+
+	page, err := s.options.paths.PageOf(rel, data)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.Dst, page), nil
+}
+
+// sourceExists returns whether a regular file at path exists and is not ignored
+func (s *Ssg) sourceExists(path string) bool {
+	stat, err := os.Stat(path)
+	if err != nil || stat.IsDir() {
+		return false
+	}
+	return !s.Ignore(path)
+}
@@ -0,0 +1,119 @@
+package ssg
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRewriteLinks(t *testing.T) {
+	build := func(t *testing.T, files map[string]string, opts ...Option) (map[string]OutputFile, error) {
+		root := t.TempDir()
+		src := filepath.Join(root, "src")
+		for path, content := range files {
+			path = filepath.Join(src, path)
+			err := os.MkdirAll(filepath.Dir(path), 0755)
+			if err != nil {
+				panic(err)
+			}
+			err = os.WriteFile(path, []byte(content), 0644)
+			if err != nil {
+				panic(err)
+			}
+		}
+		s := NewWithOptions(src, filepath.Join(root, "dst"), "TestRewriteLinks", "https://example.com",
+			append([]Option{Caching(true)}, opts...)...,
+		)
+		_, cache, err := s.Build(nil)
+		outputs := make(map[string]OutputFile)
+		for _, o := range cache {
+			rel, err := filepath.Rel(s.Dst, o.target)
+			if err != nil {
+				panic(err)
+			}
+			outputs[filepath.ToSlash(rel)] = o
+		}
+		return outputs, err
+	}
+
+	files := map[string]string{
+		"index.md": `# Home
+
+[A](blog/a.md) [B](blog/b.md#sec) [Blog](blog/index.md) [C](blog/c.md)
+[Other site](https://example.org/foo.md) [Absolute](/blog/a.md) [Image](img.png)
+`,
+		"blog/index.md": "# Blog\n\n[Home](../index.md)\n",
+		"blog/a.md":     "# A\n\n[Home](../index.md) [B](b.md)\n",
+		"blog/b.md":     ":ssg-slug bee\n# B\n\n## Sec\n",
+		"blog/c.md":     "# C from Markdown",
+		"blog/c.html":   "<h1>C from HTML</h1>",
+		"img.png":       "png",
+	}
+
+	type testCase struct {
+		paths    PathStrategy
+		expected map[string][]string // Output page and hrefs expected in the page
+	}
+
+	tests := []testCase{
+		{
+			paths: PathsUgly,
+			expected: map[string][]string{
+				"index.html": {
+					"blog/a.html", "blog/bee.html#sec", "blog/index.html", "blog/c.html",
+					"https://example.org/foo.md", "/blog/a.md",
+				},
+				"blog/index.html": {"../index.html"},
+				"blog/a.html":     {"../index.html", "bee.html"},
+			},
+		},
+		{
+			paths: PathsPretty,
+			expected: map[string][]string{
+				"index.html": {
+					"blog/a/", "blog/bee/#sec", "blog/", "blog/c.html",
+					"https://example.org/foo.md", "/blog/a.md",
+				},
+				"blog/index.html":   {"../"},
+				"blog/a/index.html": {"../../", "../bee/"},
+			},
+		},
+	}
+
+	for i := range tests {
+		tc := &tests[i]
+		outputs, err := build(t, files, RewriteLinks(true), WithPathStrategy(tc.paths))
+		if err != nil {
+			t.Fatalf("[%s] unexpected error: %v", tc.paths, err)
+		}
+		for page, hrefs := range tc.expected {
+			o, ok := outputs[page]
+			if !ok {
+				t.Fatalf("[%s] missing output %s", tc.paths, page)
+			}
+			for _, href := range hrefs {
+				if !bytes.Contains(o.data, []byte(fmt.Sprintf(`href="%s"`, href))) {
+					t.Fatalf("[%s] missing href '%s' in %s:\n%s", tc.paths, href, page, o.data)
+				}
+			}
+		}
+	}
+
+	outputs, err := build(t, files)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Contains(outputs["index.html"].data, []byte(`href="blog/a.md"`)) {
+		t.Fatalf("unexpected rewritten link without RewriteLinks: %s", outputs["index.html"].data)
+	}
+
+	_, err = build(t, map[string]string{
+		"index.md": "# Home\n\n[Missing](missing.md)\n",
+	}, RewriteLinks(true))
+	if err == nil || !strings.Contains(err.Error(), "missing.md") {
+		t.Fatalf("expecting error from link to nonexistent source, got %v", err)
+	}
+}
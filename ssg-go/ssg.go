@@ -5,19 +5,22 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/gomarkdown/markdown/html"
 	"github.com/gomarkdown/markdown/parser"
-	ignore "github.com/sabhiram/go-gitignore"
 )
 
 const (
 	MarkerHeader = "_header.html"
 	MarkerFooter = "_footer.html"
 	SsgIgnore    = ".ssgignore"
+	SsgDotFiles  = ".files"
+	SsgRedirects = "_redirects"
 
 	WritersEnvKey      = "SSG_WRITERS"
 	WritersDefault int = 20
@@ -58,36 +61,60 @@ type Ssg struct {
 
 	options options
 
-	ssgignores func(path string) (ignore bool)
-	headers    headers
-	footers    footers
-	preferred  Set // Used to prefer html and ignore md files with identical names, as with the original ssg
+	ignorer   *Ignorer
+	headers   headers
+	footers   footers
+	preferred Set               // Used to prefer html and ignore md files with identical names, as with the original ssg
+	pages     map[string]string // Maps page targets to their originators, used to detect duplicate targets
+	redirects []redirect        // Redirects from page aliases and site-wide redirects, listed in _redirects
 
-	result buildOutput
+	result    buildOutput
+	report    *Report
+	following []string // Resolved symlinked directories being walked
 }
 
 func (s *Ssg) Options() Options { return s.options }
 func (s *Ssg) Outputs() Outputs { return &s.result }
 
+// Report returns report of the last build, or nil if s was never built.
+func (s *Ssg) Report() *Report { return s.report }
+
+// Logger returns the logger injected with [WithLogger], or nil.
+func (s *Ssg) Logger() *slog.Logger { return s.options.logger }
+
+// PathStrategy returns output paths strategy set with [WithPathStrategy].
+func (s *Ssg) PathStrategy() PathStrategy { return s.options.paths }
+
 // New returns a default [Ssg] with options.
+// It panics on errors from [Prepare].
 func New(src, dst, title, url string) Ssg {
+	s, err := Prepare(src, dst, title, url)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Prepare is like [New], but returns errors from empty or identical src and dst,
+// or from parsing .ssgignore under src, instead of panicking.
+func Prepare(src, dst, title, url string) (Ssg, error) {
 	src = filepath.Clean(src)
 	dst = filepath.Clean(dst)
-	ignores, err := prepare(src, dst)
+	ignorer, err := prepare(src, dst)
 	if err != nil {
-		panic(err)
+		return Ssg{}, err
 	}
 	s := Ssg{
-		Src:        src,
-		Dst:        dst,
-		Title:      title,
-		Url:        url,
-		ssgignores: ignores.Ignore,
-		preferred:  make(Set),
-		headers:    newHeaders(HeaderDefault),
-		footers:    newFooters(FooterDefault),
+		Src:       src,
+		Dst:       dst,
+		Title:     title,
+		Url:       url,
+		ignorer:   ignorer,
+		preferred: make(Set),
+		headers:   newHeaders(HeaderDefault),
+		footers:   newFooters(FooterDefault),
 	}
-	return s
+	return s, nil
 }
 
 func NewWithOptions(src, dst, title, url string, opts ...Option) *Ssg {
@@ -207,16 +234,32 @@ func (s *Ssg) core(path string, data []byte, d fs.DirEntry) (OutputFile, error)
 		return OutputFile{}, err
 	}
 	for i, hook := range s.options.hooks {
+		start := time.Now()
 		data, err = hook(path, data)
+		s.report.Timings.Hooks[i] += time.Since(start)
 		if err != nil {
 			return OutputFile{}, fmt.Errorf("hooks[%d]: error when building %s: %w", i, path, err)
 		}
 	}
 
+	ext := filepath.Ext(path)
+	preferHtml, custom, target := false, false, ""
+	if ext == ".md" {
+		target, custom, err = s.pageTarget(path, data)
+		if err != nil {
+			return OutputFile{}, err
+		}
+		preferHtml, err = s.preferHtml(path, target, custom)
+		if err != nil {
+			return OutputFile{}, err
+		}
+	}
+
 	// Copy non-Markdown and HTML files
-	if ext := filepath.Ext(path); ext != ".md" || s.preferred.Contains(
-		ChangeExt(path, ".md", ".html"),
-	) {
+	if ext != ".md" || preferHtml {
+		if ext == ".md" {
+			s.report.warn("markdown '%s' copied as-is because its html counterpart is preferred", path)
+		}
 		target, err := mirrorPath(s.Src, s.Dst, path)
 		if err != nil {
 			return OutputFile{}, err
@@ -230,12 +273,21 @@ func (s *Ssg) core(path string, data []byte, d fs.DirEntry) (OutputFile, error)
 		), nil
 	}
 
-	target, err := mirrorPath(s.Src, s.Dst, path)
+	err = s.claimPage(target, path)
 	if err != nil {
 		return OutputFile{}, err
 	}
+	if custom {
+		data = removeTag(removeTag(data, keyPermalink), keySlug)
+	}
+	if aliases := GetAliases(data); len(aliases) != 0 {
+		err = s.alias(target, path, aliases)
+		if err != nil {
+			return OutputFile{}, err
+		}
+		data = removeTags(data, keyAlias)
+	}
 
-	target = ChangeExt(target, ".md", ".html")
 	header := s.headers.choose(path)
 	footer := s.footers.choose(path)
 
@@ -251,13 +303,20 @@ func (s *Ssg) core(path string, data []byte, d fs.DirEntry) (OutputFile, error)
 		headerText, data = AddTitleFromTag([]byte(s.Title), headerText, data)
 	}
 
+	body, err := s.toHtml(path, target, data)
+	if err != nil {
+		return OutputFile{}, err
+	}
+
 	// HTML output buffer
 	buf := bytes.NewBuffer(headerText)
-	buf.Write(ToHtml(data))
+	buf.Write(body)
 	buf.Write(footer.Bytes())
 
 	for i, h := range s.options.hookGenerate {
+		start := time.Now()
 		b, err := h(buf.Bytes())
+		s.report.Timings.HooksGenerate[i] += time.Since(start)
 		if err != nil {
 			return OutputFile{}, fmt.Errorf("hooksGenerate[%d] error when building %s: %w", i, path, err)
 		}
@@ -272,15 +331,49 @@ func (s *Ssg) core(path string, data []byte, d fs.DirEntry) (OutputFile, error)
 	), nil
 }
 
+// Ignore returns whether path is ignored, either as a dotfile
+// not allowed by [AllowDotfiles], or by .ssgignore files under s.Src
 func (s *Ssg) Ignore(path string) bool {
-	return s.ssgignores(path)
+	isDir := false
+	if stat, err := os.Lstat(path); err == nil {
+		isDir = stat.IsDir()
+	}
+	reason, _, err := s.shouldIgnore(path, filepath.Base(path), isDir)
+	if err != nil && !errors.Is(err, fs.SkipDir) {
+		return false
+	}
+	if reason != "" || !isDir {
+		return reason != ""
+	}
+	// Ignored directories are still walked by builds
+	ignored, _, err := s.ignorer.Match(path, isDir)
+	return err == nil && ignored
+}
+
+// IgnoredBy returns whether path is ignored by .ssgignore files under s.Src,
+// and the rule that decided it. See [Ignorer.Match].
+func (s *Ssg) IgnoredBy(path string, isDir bool) (bool, *IgnoreRule, error) {
+	return s.ignorer.Match(path, isDir)
 }
 
 func (s *Ssg) pront(l int) {
+	if s.options.logger != nil {
+		s.options.logger.Info("[ssg-go] done", "count", l, "dst", s.Dst)
+		return
+	}
 	Fprintf(os.Stdout, "[ssg-go] wrote %d file(s) to %s\n", l, s.Dst)
 }
 
-func prepare(src, dst string) (*gitIgnorer, error) {
+// written reports o as written to its target
+func (s *Ssg) written(o *OutputFile) {
+	if s.options.logger != nil {
+		s.options.logger.Info("[ssg-go] wrote", "target", o.target, "originator", o.originator, "size", len(o.data))
+		return
+	}
+	Fprintln(os.Stdout, o.target)
+}
+
+func prepare(src, dst string) (*Ignorer, error) {
 	if src == "" {
 		return nil, fmt.Errorf("empty src")
 	}
@@ -291,66 +384,95 @@ func prepare(src, dst string) (*gitIgnorer, error) {
 		return nil, fmt.Errorf("src is identical to dst: '%s'", src)
 	}
 
-	ssgignore := filepath.Join(src, SsgIgnore)
-	return ParseSsgIgnore(ssgignore)
-}
-
-func ParseSsgIgnore(path string) (*gitIgnorer, error) {
-	ignores, err := ignore.CompileIgnoreFile(path)
+	// Nested .ssgignore files are loaded lazily during the walk
+	ignorer := NewIgnorer(src)
+	_, err := ignorer.load(src)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("failed to parse ssgignore at %s: %w", path, err)
+		return nil, err
 	}
-
-	return &gitIgnorer{GitIgnore: ignores}, nil
-}
-
-type gitIgnorer struct {
-	*ignore.GitIgnore
+	return ignorer, nil
 }
 
-func (i *gitIgnorer) Ignore(path string) bool {
-	if i == nil {
-		return false
-	}
-	if i.GitIgnore == nil {
-		return false
+// shouldIgnore returns the reason path should be ignored,
+// or an empty string if path is not to be ignored.
+// If path is ignored by .ssgignore, the matching rule is also returned.
+//
+// If path is an ignored directory, the error will be fs.SkipDir.
+func (s *Ssg) shouldIgnore(path, base string, isDir bool) (string, *IgnoreRule, error) {
+	var skip error
+	if isDir {
+		skip = fs.SkipDir
 	}
-	return i.MatchesPath(path)
-}
-
-// TODO: refactor
-func shouldIgnore(ignoreFn func(path string) (ignored bool), path, base string, d fs.DirEntry) (bool, error) {
-	isDot := strings.HasPrefix(base, ".")
-	isDir := d.IsDir()
-
-	switch {
-	case isDot && isDir:
-		return true, fs.SkipDir
 
-	// Ignore hidden files and dir
-	case isDot, isDir:
-		return true, nil
+	// Ignore hidden files, unless allowed
+	if strings.HasPrefix(base, ".") {
+		allowed, err := s.allowDot(path, base)
+		if err != nil {
+			return "", nil, err
+		}
+		if !allowed {
+			return SkipReasonDot, nil, skip
+		}
+	}
 
-	case ignoreFn(path):
-		return true, nil
+	// Files under ignored directories may be re-included,
+	// so directories are always walked
+	if isDir {
+		return "", nil, nil
+	}
+	ignored, rule, err := s.ignorer.Match(path, isDir)
+	if err != nil {
+		return "", nil, err
+	}
+	if ignored {
+		return SkipReasonSsgIgnore, rule, nil
 	}
 
-	// Ignore symlink
-	stat, err := os.Stat(path)
+	// Symlinks are handled later according to symlink policy,
+	// so we only check if the link itself still exists
+	_, err = os.Lstat(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return true, nil
+			return SkipReasonNotExist, nil, nil
 		}
-		return false, err
+		return "", nil, err
 	}
 
-	if FileIs(stat, os.ModeSymlink) {
-		return true, nil
+	return "", nil, nil
+}
+
+// allowDot returns whether dot path is allowed by [AllowDotfiles] patterns.
+// Patterns without "/" are matched against base name,
+// while others are matched against path relative to s.Src.
+//
+// .ssgignore and .files are reserved, and are never allowed.
+func (s *Ssg) allowDot(path, base string) (bool, error) {
+	switch base {
+	case SsgIgnore, SsgDotFiles:
+		return false, nil
+	}
+	if len(s.options.dotfiles) == 0 {
+		return false, nil
 	}
 
+	rel, err := filepath.Rel(s.Src, path)
+	if err != nil {
+		return false, err
+	}
+	rel = filepath.ToSlash(rel)
+	for _, pattern := range s.options.dotfiles {
+		name := base
+		if strings.Contains(pattern, "/") {
+			name = rel
+		}
+		ok, err := filepath.Match(strings.TrimPrefix(pattern, "/"), name)
+		if err != nil {
+			return false, fmt.Errorf("bad dotfile pattern '%s': %w", pattern, err)
+		}
+		if ok {
+			return true, nil
+		}
+	}
 	return false, nil
 }
 
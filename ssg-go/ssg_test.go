@@ -6,10 +6,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"testing"
-
-	ignore "github.com/sabhiram/go-gitignore"
 )
 
 func TestToHTML(t *testing.T) {
@@ -279,10 +278,11 @@ func testGenerate(t *testing.T, buildFn func(s *Ssg) ([]string, []OutputFile, er
 	}
 }
 
-// Test that the library we use actually does what we want it to
+// Test that .ssgignore rules follow .gitignore semantics, see Ignorer
 func TestSsgignore(t *testing.T) {
 	type testCase struct {
 		path     string
+		isDir    bool
 		ignores  []string
 		expected bool
 	}
@@ -299,7 +299,8 @@ func TestSsgignore(t *testing.T) {
 			ignores: []string{
 				"testignore",
 			},
-			path:     "testignore/",
+			path:     "testignore",
+			isDir:    true,
 			expected: true,
 		},
 		{
@@ -321,7 +322,8 @@ func TestSsgignore(t *testing.T) {
 				"testignore",
 				"!prefix/testignore",
 			},
-			path:     "prefix/testignore/",
+			path:     "prefix/testignore",
+			isDir:    true,
 			expected: false,
 		},
 		{
@@ -329,10 +331,12 @@ func TestSsgignore(t *testing.T) {
 				"!prefix/testignore",
 				"testignore",
 			},
-			path:     "prefix/testignore/",
+			path:     "prefix/testignore",
+			isDir:    true,
 			expected: true,
 		},
 		{
+			// Unlike with git, files under ignored directories can be re-included
 			ignores: []string{
 				"testignore",
 				"!testignore/important/",
@@ -340,6 +344,14 @@ func TestSsgignore(t *testing.T) {
 			path:     "testignore/important/data",
 			expected: false,
 		},
+		{
+			ignores: []string{
+				"testignore/*",
+				"!testignore/important/",
+			},
+			path:     "testignore/important/data",
+			expected: false,
+		},
 		{
 			ignores: []string{
 				"testignore",
@@ -356,17 +368,82 @@ func TestSsgignore(t *testing.T) {
 			path:     "testignore/trash/some/path/keep/data",
 			expected: true,
 		},
+		{
+			ignores: []string{
+				"/testignore",
+			},
+			path:     "prefix/testignore",
+			expected: false,
+		},
+		{
+			ignores: []string{
+				"**/trash",
+			},
+			path:     "prefix/some/trash",
+			expected: true,
+		},
+		{
+			ignores: []string{
+				"a/**/b",
+			},
+			path:     "a/b",
+			expected: true,
+		},
+		{
+			ignores: []string{
+				"a/**/b",
+			},
+			path:     "a/x/y/b",
+			expected: true,
+		},
+		{
+			// Trailing slash only matches directories
+			ignores: []string{
+				"build/",
+			},
+			path:     "build",
+			expected: false,
+		},
+		{
+			ignores: []string{
+				"build/",
+			},
+			path:     "prefix/build",
+			isDir:    true,
+			expected: true,
+		},
+		{
+			ignores: []string{
+				"*.md",
+				"!keep.md",
+			},
+			path:     "some/keep.md",
+			expected: false,
+		},
+		{
+			ignores: []string{
+				`\!important.md`,
+				`\#hash.md`,
+			},
+			path:     "#hash.md",
+			expected: true,
+		},
 	}
 
+	root := filepath.Join(t.TempDir(), "root")
 	for i := range tests {
 		tc := &tests[i]
-		ignores := ignore.CompileIgnoreLines(tc.ignores...)
-		if ignores == nil {
+		rules, err := parseIgnoreRules("test", []byte(strings.Join(tc.ignores, "\n")))
+		if err != nil {
 			panic("bad ignore lines")
 		}
 
-		ignorer := &gitIgnorer{GitIgnore: ignores}
-		ignored := ignorer.Ignore(tc.path)
+		ignorer := NewIgnorer(root)
+		ignorer.rules[root] = rules
+		ignored, _, err := ignorer.Match(filepath.Join(root, tc.path), tc.isDir)
+		if err != nil {
+			t.Fatalf("[case %d] unexpected error: %v", i+1, err)
+		}
 		if tc.expected == ignored {
 			continue
 		}
@@ -375,6 +452,111 @@ func TestSsgignore(t *testing.T) {
 	}
 }
 
+func TestAllowDotfiles(t *testing.T) {
+	src := t.TempDir()
+	files := []string{
+		".well-known/acme-challenge/token",
+		".well-known/security.txt",
+		".well-known/.hidden",
+		".htaccess",
+		".git/config",
+		"blog/.htaccess",
+		"blog/.draft.md",
+		"blog/index.md",
+		".ssgignore",
+		".files",
+	}
+	for _, path := range files {
+		path = filepath.Join(src, path)
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			panic(err)
+		}
+		err = os.WriteFile(path, []byte(path), 0644)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	type testCase struct {
+		patterns []string
+		expected []string // Expected outputs relative to dst
+	}
+
+	tests := []testCase{
+		{
+			patterns: nil,
+			expected: []string{"blog/index.html"},
+		},
+		{
+			patterns: []string{"/.well-known"},
+			expected: []string{
+				".well-known/acme-challenge/token",
+				".well-known/security.txt",
+				"blog/index.html",
+			},
+		},
+		{
+			patterns: []string{".well-known", ".htaccess"},
+			expected: []string{
+				".htaccess",
+				".well-known/acme-challenge/token",
+				".well-known/security.txt",
+				"blog/.htaccess",
+				"blog/index.html",
+			},
+		},
+		{
+			// .ssgignore and .files are reserved
+			patterns: []string{".*"},
+			expected: []string{
+				".git/config",
+				".htaccess",
+				".well-known/.hidden",
+				".well-known/acme-challenge/token",
+				".well-known/security.txt",
+				"blog/.draft.html",
+				"blog/.htaccess",
+				"blog/index.html",
+			},
+		},
+	}
+
+	for i := range tests {
+		tc := &tests[i]
+		dst := filepath.Join(t.TempDir(), "dst")
+		s := NewWithOptions(src, dst, "TestAllowDotfiles", "https://example.com",
+			Caching(true),
+			AllowDotfiles(tc.patterns...),
+		)
+		_, outputs, err := s.Build(nil)
+		if err != nil {
+			t.Fatalf("[case %d] unexpected error: %v", i+1, err)
+		}
+
+		var actual []string
+		for _, o := range outputs {
+			rel, err := filepath.Rel(dst, o.target)
+			if err != nil {
+				panic(err)
+			}
+			actual = append(actual, filepath.ToSlash(rel))
+		}
+		sort.Strings(actual)
+		if strings.Join(actual, ",") != strings.Join(tc.expected, ",") {
+			t.Fatalf("[case %d] unexpected outputs\nexpected=%v\nactual=%v", i+1, tc.expected, actual)
+		}
+	}
+
+	s := NewWithOptions(src, filepath.Join(t.TempDir(), "dst"), "TestAllowDotfiles", "https://example.com",
+		AllowDotfiles("[bad"),
+	)
+	_, _, err := s.Build(nil)
+	if err == nil {
+		t.Fatal("expecting error from bad dotfile pattern")
+	}
+}
+
 // TestBuildAndWriteOut tests that Build+WriteOut both
 // work as expected (identical to Generate)
 func TestBuildAndWriteOut(t *testing.T) {
@@ -0,0 +1,180 @@
+package ssg
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SymlinkPolicy determines how ssg-go handles symlinks found under src
+type SymlinkPolicy string
+
+const (
+	// SymlinkSkip skips all symlinks. This is the default policy.
+	SymlinkSkip SymlinkPolicy = "skip"
+
+	// SymlinkFollow treats symlinks as their targets,
+	// and walks into symlinked directories as if they were under src.
+	// Symlinks to directories already being walked are skipped to avoid cycles.
+	SymlinkFollow SymlinkPolicy = "follow"
+
+	// SymlinkCopy recreates symlinks in dst without reading their targets.
+	// Relative links pointing to somewhere under src are kept as-is,
+	// while other links are made absolute so that they remain valid in dst.
+	SymlinkCopy SymlinkPolicy = "copy"
+)
+
+// ParseSymlinkPolicy parses s into a [SymlinkPolicy].
+// An empty string is parsed as [SymlinkSkip].
+func ParseSymlinkPolicy(s string) (SymlinkPolicy, error) {
+	switch p := SymlinkPolicy(s); p {
+	case "":
+		return SymlinkSkip, nil
+	case SymlinkSkip, SymlinkFollow, SymlinkCopy:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown symlink policy '%s', expecting one of %s, %s or %s", s, SymlinkSkip, SymlinkFollow, SymlinkCopy)
+}
+
+// symlink handles symlink at path according to the symlink policy.
+//
+// It returns a DirEntry of the link target if the target is a file
+// to be built as if it was at path, or nil if the link was already handled.
+func (s *Ssg) symlink(path string) (fs.DirEntry, error) {
+	switch s.options.symlinks {
+	case SymlinkCopy:
+		link, err := ReadLink(s.Src, path)
+		if err != nil {
+			return nil, err
+		}
+		target, err := mirrorPath(s.Src, s.Dst, path)
+		if err != nil {
+			return nil, err
+		}
+
+		s.result.files = append(s.result.files, path)
+		s.report.input(path)
+		s.result.Add(OutputSymlink(target, path, link))
+		return nil, nil
+
+	case SymlinkFollow:
+		stat, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				s.report.skip(path, SkipReasonNotExist, nil)
+				return nil, nil
+			}
+			return nil, err
+		}
+		if !stat.IsDir() {
+			return fs.FileInfoToDirEntry(stat), nil
+		}
+		return nil, s.followDir(path)
+	}
+
+	s.report.skip(path, SkipReasonSymlink, nil)
+	return nil, nil
+}
+
+// followDir walks symlinked directory at path
+func (s *Ssg) followDir(path string) error {
+	real, err := RealPath(path)
+	if err != nil {
+		return err
+	}
+	cycle, err := s.isCycle(path, real)
+	if err != nil {
+		return err
+	}
+	if cycle {
+		s.report.skip(path, SkipReasonSymlinkCycle, nil)
+		s.report.warn("symlink '%s' to '%s' skipped because it would cause a cycle", path, real)
+		return nil
+	}
+
+	ignored, rule, err := s.ignorer.Match(path, true)
+	if err != nil {
+		return err
+	}
+	if ignored {
+		s.report.skip(path, SkipReasonSsgIgnore, rule)
+		return nil
+	}
+
+	err = s.collect(path)
+	if err != nil {
+		return err
+	}
+
+	s.following = append(s.following, real)
+	defer func() { s.following = s.following[:len(s.following)-1] }()
+
+	// WalkDir does not follow root if it's a symlink,
+	// unless it has a trailing separator
+	root := path + string(filepath.Separator)
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if p == root {
+			return err
+		}
+		return s.walk(p, d, err)
+	})
+}
+
+// isCycle returns whether walking into real, the resolved target of path,
+// would walk into a directory that is already being walked
+func (s *Ssg) isCycle(path, real string) (bool, error) {
+	parent, err := RealPath(filepath.Dir(path))
+	if err != nil {
+		return false, err
+	}
+	src, err := RealPath(s.Src)
+	if err != nil {
+		return false, err
+	}
+
+	walking := append([]string{src, parent}, s.following...)
+	for _, dir := range walking {
+		if IsAncestor(real, dir) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ReadLink reads symlink at path under root, for the link to be recreated
+// in a copy of root. Relative links pointing to somewhere under root
+// are returned as-is, while other relative links are made absolute.
+func ReadLink(root, path string) (string, error) {
+	link, err := os.Readlink(path)
+	if err != nil {
+		return "", err
+	}
+	if filepath.IsAbs(link) {
+		return link, nil
+	}
+	abs := filepath.Join(filepath.Dir(path), link)
+	if IsAncestor(root, abs) {
+		return link, nil
+	}
+	return filepath.Abs(abs)
+}
+
+// RealPath returns absolute path of path with all symlinks resolved
+func RealPath(path string) (string, error) {
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(real)
+}
+
+// IsAncestor returns whether dir is path or an ancestor of path
+func IsAncestor(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
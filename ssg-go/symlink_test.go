@@ -0,0 +1,192 @@
+package ssg
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSymlinks(t *testing.T) {
+	root := t.TempDir()
+	shared := filepath.Join(root, "shared")
+	src := filepath.Join(root, "src")
+	files := map[string]string{
+		"shared/style.css":     "body {}",
+		"shared/img/logo.svg":  "<svg></svg>",
+		"shared/page.md":       "# Shared page",
+		"src/index.md":         "# Index",
+		"src/blog/hello.md":    "# Hello",
+		"src/blog/link-me.txt": "linked",
+	}
+	for path, content := range files {
+		path = filepath.Join(root, path)
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			panic(err)
+		}
+		err = os.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			panic(err)
+		}
+	}
+	links := map[string]string{
+		"src/assets":      shared,              // Directory outside of src
+		"src/page.md":     "../shared/page.md", // Relative link outside of src
+		"src/blog/me.txt": "link-me.txt",       // Relative link inside src
+		"src/blog/loop":   "..",                // Cycle
+		"src/broken":      "nonexistent",
+	}
+	for path, link := range links {
+		err := os.Symlink(link, filepath.Join(root, path))
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	build := func(t *testing.T, p SymlinkPolicy) (*Ssg, map[string]OutputFile) {
+		s := NewWithOptions(src, filepath.Join(root, "dst"), "TestSymlinks", "https://example.com",
+			Caching(true),
+			WithSymlinks(p),
+		)
+		_, cache, err := s.Build(nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		outputs := make(map[string]OutputFile)
+		for _, o := range cache {
+			rel, err := filepath.Rel(s.Dst, o.target)
+			if err != nil {
+				panic(err)
+			}
+			outputs[rel] = o
+		}
+		return s, outputs
+	}
+
+	skipped := func(s *Ssg, reason string) Set {
+		set := make(Set)
+		for _, sk := range s.Report().Skipped {
+			if sk.Reason != reason {
+				continue
+			}
+			rel, err := filepath.Rel(src, sk.Path)
+			if err != nil {
+				panic(err)
+			}
+			set.Insert(rel)
+		}
+		return set
+	}
+
+	t.Run("skip", func(t *testing.T) {
+		s, outputs := build(t, SymlinkSkip)
+		for _, path := range []string{"index.html", "blog/hello.html", "blog/link-me.txt"} {
+			if _, ok := outputs[path]; !ok {
+				t.Fatalf("missing output %s", path)
+			}
+		}
+		for _, path := range []string{"assets/style.css", "page.html", "blog/me.txt", "broken"} {
+			if _, ok := outputs[path]; ok {
+				t.Fatalf("unexpected output %s", path)
+			}
+		}
+		if !skipped(s, SkipReasonSymlink).Contains("assets", "page.md", "blog/me.txt", "blog/loop", "broken") {
+			t.Fatalf("unexpected skipped symlinks: %+v", s.Report().Skipped)
+		}
+	})
+
+	t.Run("follow", func(t *testing.T) {
+		s, outputs := build(t, SymlinkFollow)
+		expected := map[string]string{
+			"assets/style.css":     "body {}",
+			"assets/img/logo.svg":  "<svg></svg>",
+			"blog/me.txt":          "linked",
+			"blog/link-me.txt":     "linked",
+			"assets/page.html":     "Shared page",
+			"page.html":            "Shared page",
+			"blog/hello.html":      "Hello",
+			"index.html":           "Index",
+			"assets/page.md":       "",
+			"blog/loop/index.html": "",
+		}
+		for path, content := range expected {
+			o, ok := outputs[path]
+			if content == "" {
+				if ok {
+					t.Fatalf("unexpected output %s", path)
+				}
+				continue
+			}
+			if !ok {
+				t.Fatalf("missing output %s", path)
+			}
+			if !bytes.Contains(o.data, []byte(content)) {
+				t.Fatalf("unexpected content for %s: %s", path, o.data)
+			}
+			if o.Perm() != 0644 {
+				t.Fatalf("unexpected permission for %s: %s", path, o.Perm())
+			}
+		}
+		if !skipped(s, SkipReasonSymlinkCycle).Contains("blog/loop") {
+			t.Fatalf("expecting cycle blog/loop to be skipped: %+v", s.Report().Skipped)
+		}
+		if !skipped(s, SkipReasonNotExist).Contains("broken") {
+			t.Fatalf("expecting broken to be skipped: %+v", s.Report().Skipped)
+		}
+	})
+
+	t.Run("copy", func(t *testing.T) {
+		_, outputs := build(t, SymlinkCopy)
+		expected := map[string]string{
+			"assets":      shared,
+			"page.md":     filepath.Join(shared, "page.md"),
+			"blog/me.txt": "link-me.txt",
+			"blog/loop":   "..",
+			"broken":      "nonexistent",
+		}
+		for path, link := range expected {
+			o, ok := outputs[path]
+			if !ok {
+				t.Fatalf("missing output %s", path)
+			}
+			if o.Link() != link {
+				t.Fatalf("unexpected link for %s: expecting '%s', got '%s'", path, link, o.Link())
+			}
+		}
+		if _, ok := outputs["page.html"]; ok {
+			t.Fatalf("unexpected output page.html")
+		}
+
+		dst := filepath.Join(root, "dst")
+		err := WriteOutSlice(mapValues(outputs), 1)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		b, err := os.ReadFile(filepath.Join(dst, "assets", "style.css"))
+		if err != nil {
+			t.Fatalf("unexpected error reading through copied symlink: %v", err)
+		}
+		if string(b) != "body {}" {
+			t.Fatalf("unexpected content through copied symlink: %s", b)
+		}
+
+		changes, err := Compare(mapValues(outputs), false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, c := range changes {
+			if c.Kind != ChangeUnchanged {
+				t.Fatalf("unexpected change after write: %+v", c)
+			}
+		}
+	})
+}
+
+func mapValues(m map[string]OutputFile) []OutputFile {
+	values := make([]OutputFile, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	return values
+}